@@ -2,9 +2,11 @@ package main
 
 import (
 	"context"
+	"time"
 
 	"github.com/gavt45/okx-exporter/pkg/app"
 	"github.com/gavt45/okx-exporter/pkg/core"
+	"github.com/gavt45/okx-exporter/pkg/core/domain/okx"
 	"github.com/gavt45/okx-exporter/pkg/log"
 	validator "github.com/go-playground/validator/v10"
 	"github.com/heetch/confita"
@@ -23,7 +25,19 @@ func main() {
 		flags.NewBackend(),
 	)
 
-	cfg := core.ServiceConfig{}
+	cfg := core.ServiceConfig{
+		OKX: core.OKXConfig{
+			PrivateWSHost:          "ws.okx.com:8443",
+			KeepaliveMode:          core.KeepaliveText,
+			MaxTopicsPerConnection: 100,
+			Instruments:            []okx.Instrument{okx.InstrumentETHxUSDT},
+			BookDepthBps:           []int{10, 50, 100},
+			Reconnect: core.ReconnectConfig{
+				InitialInterval: time.Second,
+				MaxInterval:     time.Minute,
+			},
+		},
+	}
 
 	err := loader.Load(context.Background(), &cfg)
 	if err != nil {
@@ -31,8 +45,24 @@ func main() {
 		return
 	}
 
+	if err = cfg.OKX.LoadSecrets(); err != nil {
+		log.Fatal("Can't load secrets: ", err.Error())
+		return
+	}
+
+	for _, secret := range cfg.OKX.Secrets() {
+		log.AddSecret(secret)
+	}
+
+	log.Debugf("Loaded config: %+v", cfg)
+
 	v := validator.New()
 
+	if err = core.RegisterValidations(v); err != nil {
+		log.Fatal("Can't register config validations: ", err.Error())
+		return
+	}
+
 	if err = v.Struct(cfg); err != nil {
 		log.Fatal("Can't load config: ", err.Error())
 		return
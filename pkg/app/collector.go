@@ -2,9 +2,12 @@ package app
 
 import (
 	"context"
-	"net"
+	"encoding/json"
+	"math/rand/v2"
 	"net/http"
 	"net/url"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gavt45/okx-exporter/pkg/core"
@@ -16,69 +19,153 @@ import (
 )
 
 const (
-	OKXPublicPath string        = "/ws/v5/ipublic"
-	ReadTimeout   time.Duration = 15 * time.Second
-	PingInterval  time.Duration = 10 * time.Second
+	OKXPublicPath  string        = "/ws/v5/ipublic"
+	OKXPrivatePath string        = "/ws/v5/private"
+	ReadTimeout    time.Duration = 15 * time.Second
+	PingInterval   time.Duration = 10 * time.Second
+	// Subscriptions not acknowledged for this interval are retried, failed ones are retried after it
+	// doubled after each failure up to max interval
+	SubscriptionRetryInterval    time.Duration = 30 * time.Second
+	SubscriptionMaxRetryInterval time.Duration = 30 * time.Minute
+	// Amount of topics sent in one subscribe request
+	SubscribeBatchSize int = 20
 )
 
-// Codes we consider irrecoverable, so we will crash when receiving them
-var irrecoverableCodes = map[int]bool{
-	websocket.CloseProtocolError:   true,
-	websocket.CloseUnsupportedData: true,
-	websocket.CloseMessageTooBig:   true,
-	websocket.ClosePolicyViolation: true,
+// Processor provides topics to subscribe and processes messages received on them
+type Processor interface {
+	Topics() []okx.WSSubscriptionTopic
+	ProcessMessage(data okx.WSData) error
 }
 
 type RecieverApp struct {
 	msgs chan okx.WSData
 
-	cfg  *core.OKXConfig
-	conn *websocket.Conn
+	cfg *core.OKXConfig
+	// name connection name, used as connection label of metrics
+	name string
+	host string
+	path string
+	// account login is done on connect with its credentials when it is set
+	account *core.AccountConfig
+	conn    *websocket.Conn
+	// writeMu guards conn writes, as websocket connection supports only one concurrent writer
+	writeMu sync.Mutex
+	// Unix nanoseconds of last sent text and control pings, used to measure round trip time
+	textPingSentAt    atomic.Int64
+	controlPingSentAt atomic.Int64
+
+	svc  Processor
+	subs *subscriptions
+}
+
+func newRecieverApp(cfg *core.OKXConfig, name, host, path string, account *core.AccountConfig) *RecieverApp {
+	return &RecieverApp{
+		cfg:     cfg,
+		name:    name,
+		host:    host,
+		path:    path,
+		account: account,
+		msgs:    make(chan okx.WSData, 100),
+		subs:    newSubscriptions(name),
+	}
+}
+
+// NewPrivateRecieverApp returns receiver of private account data, it is connected and logged in by Start,
+// so failing account doesn't prevent other receivers from starting
+func NewPrivateRecieverApp(cfg *core.OKXConfig, account *core.AccountConfig) *RecieverApp {
+	app := newRecieverApp(cfg, "private/"+account.Name, cfg.PrivateWSHost, OKXPrivatePath, account)
+	app.svc = core.NewAccountService(account.Name)
 
-	svc *core.Service
+	return app
 }
 
-func NewRecieverApp(cfg *core.OKXConfig) (*RecieverApp, error) {
-	var err error
+// writeJSON writes message to connection with write deadline
+func (a *RecieverApp) writeJSON(v interface{}) error {
+	a.writeMu.Lock()
+	defer a.writeMu.Unlock()
 
-	app := &RecieverApp{
-		cfg:  cfg,
-		msgs: make(chan okx.WSData, 100),
-		svc:  &core.Service{},
+	// connection of shard created by pool is not dialed yet, topics are subscribed on connect
+	if a.conn == nil {
+		return errors.New("not connected")
 	}
 
-	err = app.connect()
+	if err := a.conn.SetWriteDeadline(time.Now().Add(ReadTimeout)); err != nil {
+		return errors.Wrap(err, "can't set write deadline")
+	}
+
+	return a.conn.WriteJSON(v)
+}
+
+// Subscribe implements core.Subscriber
+func (a *RecieverApp) Subscribe(topics ...okx.WSSubscriptionTopic) error {
+	err := a.writeJSON(&okx.WSRequest{
+		ID:   a.subs.pending(topics),
+		Op:   okx.OperationSubscribe,
+		Args: topics,
+	})
 	if err != nil {
-		return nil, err
+		return errors.Wrap(err, "can't write subscribe request")
 	}
 
-	return app, err
+	return nil
 }
 
-func (a *RecieverApp) subscribeToChannel(instrument okx.Instrument, channel okx.Channel) error {
-	err := a.conn.WriteJSON(&okx.WSRequest{
-		Op: okx.OperationSubscribe,
-		Args: []okx.WSSubscriptionTopic{
-			{
-				WSArgument: okx.WSArgument{
-					Channel: channel,
-				},
-				InstID: instrument,
-			},
-		},
+// Unsubscribe implements core.Subscriber
+func (a *RecieverApp) Unsubscribe(topics ...okx.WSSubscriptionTopic) error {
+	a.subs.remove(topics)
+
+	err := a.writeJSON(&okx.WSRequest{
+		Op:   okx.OperationUnsubscribe,
+		Args: topics,
 	})
 	if err != nil {
-		return errors.Wrap(err, "can't subscribe to "+string(channel))
+		return errors.Wrap(err, "can't write unsubscribe request")
 	}
 
 	return nil
 }
 
-// subscribeToRequiredChannels subscribes to all channels, required by core app
+// Resubscribe implements core.Subscriber
+func (a *RecieverApp) Resubscribe(topic okx.WSSubscriptionTopic) error {
+	if err := a.Unsubscribe(topic); err != nil {
+		return err
+	}
+
+	return a.Subscribe(topic)
+}
+
+// subscribeBatched subscribes to topics with requests of SubscribeBatchSize topics
+func (a *RecieverApp) subscribeBatched(topics []okx.WSSubscriptionTopic) error {
+	for start := 0; start < len(topics); start += SubscribeBatchSize {
+		end := min(start+SubscribeBatchSize, len(topics))
+
+		if err := a.Subscribe(topics[start:end]...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// subscribeToRequiredChannels subscribes to all topics, required by core app
 func (a *RecieverApp) subscribeToRequiredChannels() error {
-	for _, channel := range a.svc.RequiredChannels() {
-		err := a.subscribeToChannel(okx.InstrumentETHxUSDT, channel)
-		if err != nil {
+	a.subs.reset()
+
+	return a.subscribeBatched(a.svc.Topics())
+}
+
+// retrySubscriptions subscribes to topics, which retry time has come, one topic per request,
+// so okx error of request can be attributed to topic
+func (a *RecieverApp) retrySubscriptions() error {
+	topics := a.subs.retryable(time.Now())
+	if len(topics) == 0 {
+		return nil
+	}
+
+	log.Infof("Retrying %d subscriptions on %s", len(topics), a.name)
+
+	for _, topic := range topics {
+		if err := a.Subscribe(topic); err != nil {
 			return err
 		}
 	}
@@ -86,20 +173,90 @@ func (a *RecieverApp) subscribeToRequiredChannels() error {
 	return nil
 }
 
-func (a *RecieverApp) connect() error {
-	var err error
+// subscriptionRetrier retries failed and not acknowledged subscriptions periodically
+// and right away when tracker asks for it
+func (a *RecieverApp) subscriptionRetrier(ctx context.Context) error {
+	ticker := time.NewTicker(SubscriptionRetryInterval)
+	defer ticker.Stop()
 
-	u := url.URL{Scheme: "wss", Host: a.cfg.WSHost, Path: OKXPublicPath}
+	for {
+		select {
+		case <-ticker.C:
+		case <-a.subs.retry:
+		case <-ctx.Done():
+			log.Debug("Subscription retrier exiting")
+			return nil
+		}
 
-	log.Debug("Dialing ", u.String())
+		if err := a.retrySubscriptions(); err != nil {
+			return err
+		}
+	}
+}
+
+// login sends login request and waits for its result
+func (a *RecieverApp) login() error {
+	log.Debug("Logging in as ", a.account.Name)
+
+	creds := a.account.Credentials
+
+	err := a.writeJSON(okx.NewWSLoginRequest(creds.APIKey, string(creds.SecretKey), string(creds.Passphrase), time.Now()))
+	if err != nil {
+		return errors.Wrap(err, "can't write login request")
+	}
+
+	for {
+		msg := okx.WSData{}
+
+		if err = a.conn.ReadJSON(&msg); err != nil {
+			return errors.Wrap(err, "can't read login response")
+		}
 
-	var resp *http.Response
+		switch msg.Event { //nolint:exhaustive // other events are not expected before login
+		case okx.OperationLogin:
+			log.Debug("Logged in")
+			return nil
+		case okx.OperationError:
+			mLoginFailures.WithLabelValues(a.account.Name, msg.Code).Inc()
+			return &okx.LoginError{Code: msg.Code, Msg: msg.Msg}
+		default:
+			log.Debug("Skipping message before login: ", msg)
+		}
+	}
+}
 
-	if a.conn, resp, err = websocket.DefaultDialer.Dial(u.String(), nil); err != nil {
+func (a *RecieverApp) connect() error {
+	u := url.URL{Scheme: "wss", Host: a.host, Path: a.path}
+
+	log.Debug("Dialing ", u.String())
+
+	conn, resp, err := websocket.DefaultDialer.Dial(u.String(), nil)
+	if err != nil {
 		return errors.Wrap(err, "can't dial websocket at "+u.String())
 	}
 
-	err = resp.Body.Close()
+	a.writeMu.Lock()
+	a.conn = conn
+	a.writeMu.Unlock()
+
+	if err = a.setup(resp); err != nil {
+		// connection is not returned to caller, so it is closed here not to leak it on retries
+		if closeErr := conn.Close(); closeErr != nil {
+			log.Debug("Got close error: ", closeErr.Error())
+		}
+
+		return err
+	}
+
+	mConnectionUp.WithLabelValues(a.name).Set(1)
+	log.Debug("Connected to ", u.String())
+
+	return nil
+}
+
+// setup prepares dialed connection: logs in if account is set and subscribes to required channels
+func (a *RecieverApp) setup(resp *http.Response) error {
+	err := resp.Body.Close()
 	if err != nil {
 		return errors.Wrap(err, "can't close websocket response body")
 	}
@@ -110,44 +267,63 @@ func (a *RecieverApp) connect() error {
 	}
 
 	a.conn.SetPongHandler(func(string) error {
-		log.Debug("Pong")
-		return a.conn.SetReadDeadline(time.Now().Add(ReadTimeout))
+		return a.handlePong(&a.controlPingSentAt, core.KeepaliveControl)
 	})
 
-	log.Debug("Subscribing to updates")
-
-	err = a.conn.SetWriteDeadline(time.Now().Add(ReadTimeout))
-	if err != nil {
-		return errors.Wrap(err, "can't set write deadline")
+	if a.account != nil {
+		if err = a.login(); err != nil {
+			return err
+		}
 	}
 
+	log.Debug("Subscribing to updates")
+
 	err = a.subscribeToRequiredChannels()
 	if err != nil {
 		return errors.Wrap(err, "can't subscribe to required channels on connect")
 	}
 
-	log.Debug("Connected to ", u.String())
-
 	return nil
 }
 
+// skipMessage counts and logs malformed message error
+func (a *RecieverApp) skipMessage(err error) {
+	msgErr := &core.MessageError{}
+	errors.As(err, &msgErr)
+
+	mMalformedMessages.WithLabelValues(a.name, string(msgErr.Channel)).Inc()
+	log.Warn("Skipping message: ", err.Error())
+}
+
 func (a *RecieverApp) reader(ctx context.Context) error {
 	done := false
 	for !done {
-		msg := okx.WSData{}
-
-		err := a.conn.ReadJSON(&msg)
+		_, payload, err := a.conn.ReadMessage()
 		if err != nil {
 			log.Debug("Got read error: ", err.Error())
 			return err
 		}
 
-		a.msgs <- msg
+		if string(payload) == okx.MessagePong {
+			if err = a.handlePong(&a.textPingSentAt, core.KeepaliveText); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		msg := okx.WSData{}
+
+		if err = json.Unmarshal(payload, &msg); err != nil {
+			a.skipMessage(&core.MessageError{Err: errors.Wrap(err, "can't decode message")})
+			continue
+		}
 
+		// processor exits on context cancellation, so send must not block after it
 		select {
+		case a.msgs <- msg:
 		case <-ctx.Done():
 			done = true
-		default:
 		}
 	}
 
@@ -156,20 +332,56 @@ func (a *RecieverApp) reader(ctx context.Context) error {
 	return nil
 }
 
+// handlePong observes round trip time since ping of given type was sent and extends read deadline
+func (a *RecieverApp) handlePong(sentAt *atomic.Int64, pingType string) error {
+	log.Debug("Pong")
+
+	if ts := sentAt.Swap(0); ts != 0 {
+		mPingRTT.WithLabelValues(a.name, pingType).Observe(time.Since(time.Unix(0, ts)).Seconds())
+	}
+
+	return a.conn.SetReadDeadline(time.Now().Add(ReadTimeout))
+}
+
+// writePing writes text "ping" message and/or ping control message, depending on keepalive mode
+func (a *RecieverApp) writePing() error {
+	a.writeMu.Lock()
+	defer a.writeMu.Unlock()
+
+	if err := a.conn.SetWriteDeadline(time.Now().Add(ReadTimeout)); err != nil {
+		return errors.Wrap(err, "can't set write deadline when pinging")
+	}
+
+	mode := a.cfg.KeepaliveMode
+
+	if mode == core.KeepaliveText || mode == core.KeepaliveBoth {
+		a.textPingSentAt.Store(time.Now().UnixNano())
+
+		if err := a.conn.WriteMessage(websocket.TextMessage, []byte(okx.MessagePing)); err != nil {
+			return err
+		}
+	}
+
+	if mode == core.KeepaliveControl || mode == core.KeepaliveBoth {
+		a.controlPingSentAt.Store(time.Now().UnixNano())
+
+		if err := a.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func (a *RecieverApp) pinger(ctx context.Context) error {
 	ticker := time.NewTicker(PingInterval)
 
 	for {
 		select {
 		case <-ticker.C:
-			// Set write timeout before sending message
-			if err := a.conn.SetWriteDeadline(time.Now().Add(ReadTimeout)); err != nil {
-				return errors.Wrap(err, "can't set write deadline when pinging")
-			}
-
 			log.Debug("Ping")
 
-			if err := a.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+			if err := a.writePing(); err != nil {
 				log.Debug("Got write error: ", err.Error())
 				ticker.Stop()
 
@@ -188,11 +400,24 @@ func (a *RecieverApp) processor(ctx context.Context) error {
 	for {
 		select {
 		case msg := <-a.msgs:
+			if msg.Event != okx.OperationEmpty {
+				a.subs.handleEvent(msg)
+				continue
+			}
+
 			err := a.svc.ProcessMessage(msg)
-			if err != nil {
-				log.Debug("Got process error: ", err.Error())
-				return err
+			if err == nil {
+				continue
+			}
+
+			if classifyError(err) == errorClassMalformed {
+				a.skipMessage(err)
+				continue
 			}
+
+			log.Debug("Got process error: ", err.Error())
+
+			return err
 		case <-ctx.Done():
 			log.Debug("Processor exiting")
 			return nil
@@ -215,10 +440,79 @@ func (a *RecieverApp) startProcessing(ctx context.Context) error {
 		return a.processor(connCtx)
 	})
 
+	g.Go(func() error {
+		return a.subscriptionRetrier(connCtx)
+	})
+
+	// reader blocks in ReadMessage until read deadline, so connection is closed to stop it as soon as
+	// any other goroutine fails or parent context is cancelled
+	g.Go(func() error {
+		<-connCtx.Done()
+
+		if err := a.conn.Close(); err != nil {
+			log.Debug("Got close error: ", err.Error())
+		}
+
+		return nil
+	})
+
 	return g.Wait()
 }
 
+// withJitter returns random duration in [d/2, d)
+func withJitter(d time.Duration) time.Duration {
+	half := d / 2
+
+	return half + rand.N(d-half) //nolint:gosec // jitter doesn't need secure random
+}
+
+// connectWithBackoff connects until it succeeds with exponential backoff. It gives up only on context
+// cancellation. Login errors are retried at max interval, so account with revoked key doesn't stop other
+// connections, while login failing because of clock skew or maintenance recovers by itself.
+func (a *RecieverApp) connectWithBackoff(ctx context.Context) error {
+	interval := a.cfg.Reconnect.InitialInterval
+
+	for {
+		mReconnectAttempts.WithLabelValues(a.name).Inc()
+
+		err := a.connect()
+		if err == nil {
+			mReconnectBackoff.WithLabelValues(a.name).Set(0)
+			return nil
+		}
+
+		loginErr := &okx.LoginError{}
+		if errors.As(err, &loginErr) {
+			interval = a.cfg.Reconnect.MaxInterval
+		}
+
+		backoff := withJitter(interval)
+
+		mConnectionUp.WithLabelValues(a.name).Set(0)
+		mReconnectBackoff.WithLabelValues(a.name).Set(backoff.Seconds())
+		log.Warnf("Can't reconnect %s, retrying in %s: %s", a.name, backoff, err.Error())
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		interval = min(interval*2, a.cfg.Reconnect.MaxInterval)
+	}
+}
+
 func (a *RecieverApp) Start(ctx context.Context) error {
+	if a.conn == nil {
+		if err := a.connectWithBackoff(ctx); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+
+			return errors.Wrap(err, "can't connect")
+		}
+	}
+
 	errs := make(chan error, 1)
 
 	go func() {
@@ -228,28 +522,34 @@ func (a *RecieverApp) Start(ctx context.Context) error {
 	for {
 		select {
 		case err := <-errs:
-			// Try to reconnect
-			var netErr net.Error
+			mConnectionUp.WithLabelValues(a.name).Set(0)
 
-			closeError := &websocket.CloseError{}
+			// processing stops without error only on context cancellation
+			if err == nil {
+				return nil
+			}
+
+			if class := classifyError(err); class != errorClassTransport {
+				log.Debugf("Collector got %s error: %s", class, err.Error())
+				return err
+			}
 
-			if (errors.As(err, &netErr) && netErr.Timeout()) ||
-				(errors.As(err, &closeError) && !irrecoverableCodes[closeError.Code]) {
-				log.Debug("Collector is handling recoverable error: ", err.Error())
+			log.Debug("Collector is handling recoverable error: ", err.Error())
 
-				if cerr := a.connect(); cerr != nil {
-					return errors.Wrap(cerr, "can't connect")
+			// connection is already closed when processing stops
+			if rerr := a.connectWithBackoff(ctx); rerr != nil {
+				if ctx.Err() != nil {
+					return nil
 				}
 
-				go func() {
-					errs <- a.startProcessing(ctx)
-				}()
-
-				log.Info("Reconnected")
-			} else {
-				log.Debug("Collector got irrecoveralbe error: ", err.Error())
-				return err
+				return errors.Wrap(rerr, "can't reconnect")
 			}
+
+			go func() {
+				errs <- a.startProcessing(ctx)
+			}()
+
+			log.Info("Reconnected ", a.name)
 		case <-ctx.Done():
 			return nil
 		}
@@ -0,0 +1,58 @@
+package app
+
+import (
+	"github.com/gavt45/okx-exporter/pkg/core"
+	"github.com/gorilla/websocket"
+	"github.com/pkg/errors"
+)
+
+type errorClass int
+
+const (
+	// errorClassFatal app can't continue working
+	errorClassFatal errorClass = iota
+	// errorClassMalformed message is counted and skipped
+	errorClassMalformed
+	// errorClassTransport connection is broken and should be restored
+	errorClassTransport
+)
+
+func (c errorClass) String() string {
+	switch c {
+	case errorClassFatal:
+		return "fatal"
+	case errorClassMalformed:
+		return "malformed"
+	case errorClassTransport:
+		return "transport"
+	}
+
+	return "unknown"
+}
+
+// Codes we consider irrecoverable, so we will crash when receiving them
+var irrecoverableCodes = map[int]bool{
+	websocket.CloseProtocolError:   true,
+	websocket.CloseUnsupportedData: true,
+	websocket.CloseMessageTooBig:   true,
+	websocket.ClosePolicyViolation: true,
+}
+
+// classifyError decides how collector handles error returned by reader, pinger or processor.
+// Only irrecoverable close codes are fatal, any other error, i.e. read limit, protocol or TLS errors,
+// is handled by reconnecting, as new connection is likely to work.
+func classifyError(err error) errorClass {
+	var (
+		msgErr   *core.MessageError
+		closeErr *websocket.CloseError
+	)
+
+	switch {
+	case errors.As(err, &msgErr):
+		return errorClassMalformed
+	case errors.As(err, &closeErr) && irrecoverableCodes[closeErr.Code]:
+		return errorClassFatal
+	default:
+		return errorClassTransport
+	}
+}
@@ -0,0 +1,73 @@
+package app
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	mLoginFailures = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "okx_login_failures_total",
+			Help: "Amount of failed logins on private endpoint, code is okx error code",
+		},
+		[]string{"account", "code"},
+	)
+
+	mMalformedMessages = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "okx_malformed_messages_total",
+			Help: "Amount of skipped messages which can't be decoded or processed",
+		},
+		[]string{"connection", "channel"},
+	)
+
+	mPingRTT = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "okx_ping_rtt_seconds",
+			Help:    "Round trip time of keepalive ping, type is text or control",
+			Buckets: []float64{0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10},
+		},
+		[]string{"connection", "type"},
+	)
+
+	mSubscriptionState = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "okx_subscription_state",
+			Help: "1 if topic subscription is in state, 0 otherwise. State is one of pending, acked, failed",
+		},
+		[]string{"connection", "channel", "inst_type", "inst_family", "instrument", "state"},
+	)
+
+	mConnectionUp = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "okx_connection_up",
+			Help: "1 if connection is established, 0 when it is being reconnected",
+		},
+		[]string{"connection"},
+	)
+
+	mConnectionTopics = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "okx_connection_topics",
+			Help: "Amount of topics assigned to public connection",
+		},
+		[]string{"connection"},
+	)
+
+	mReconnectAttempts = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "okx_reconnect_attempts_total",
+			Help: "Amount of reconnect attempts",
+		},
+		[]string{"connection"},
+	)
+
+	mReconnectBackoff = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "okx_reconnect_backoff_seconds",
+			Help: "Current delay before next reconnect attempt, 0 when connected",
+		},
+		[]string{"connection"},
+	)
+)
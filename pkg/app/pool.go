@@ -0,0 +1,287 @@
+package app
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gavt45/okx-exporter/pkg/core"
+	"github.com/gavt45/okx-exporter/pkg/core/domain/okx"
+	"github.com/gavt45/okx-exporter/pkg/log"
+	"golang.org/x/sync/errgroup"
+)
+
+// OKX allows 3 connection requests per second from IP, so initial connections are started with this interval
+const ConnectInterval time.Duration = 400 * time.Millisecond
+
+// ConnectionPool distributes public topics across connections with at most MaxTopicsPerConnection topics each.
+// Connections are reconnected independently, messages of all of them are processed by one service.
+type ConnectionPool struct {
+	cfg *core.OKXConfig
+
+	// procMu serializes message processing, as service is shared by connections
+	procMu sync.Mutex
+	svc    Processor
+
+	// mu guards shards and topics
+	mu     sync.Mutex
+	shards []*RecieverApp
+	// topics assigned to each of shards, shard subscribes to them on connect
+	topics []map[okx.WSArgument]okx.WSSubscriptionTopic
+
+	// added receives shards created by Subscribe, they are connected and started by Start
+	added chan *RecieverApp
+	// done is closed when Start stops accepting added shards
+	done chan struct{}
+}
+
+// shardProcessor provides topics assigned to shard and processes its messages with shared service
+type shardProcessor struct {
+	pool  *ConnectionPool
+	shard int
+}
+
+func (s shardProcessor) Topics() []okx.WSSubscriptionTopic {
+	return s.pool.shardTopics(s.shard)
+}
+
+func (s shardProcessor) ProcessMessage(data okx.WSData) error {
+	s.pool.procMu.Lock()
+	defer s.pool.procMu.Unlock()
+
+	return s.pool.svc.ProcessMessage(data)
+}
+
+// NewConnectionPool returns pool of public market data receivers, they are connected by Start
+func NewConnectionPool(cfg *core.OKXConfig) (*ConnectionPool, error) {
+	var err error
+
+	pool := &ConnectionPool{
+		cfg:   cfg,
+		added: make(chan *RecieverApp),
+		done:  make(chan struct{}),
+	}
+
+	pool.svc, err = core.NewService(cfg, pool)
+	if err != nil {
+		return nil, err
+	}
+
+	pool.assign(pool.svc.Topics())
+
+	return pool, nil
+}
+
+// newShard adds receiver without topics, must be called with mu locked
+func (p *ConnectionPool) newShard() *RecieverApp {
+	idx := len(p.shards)
+
+	shard := newRecieverApp(p.cfg, "public/"+strconv.Itoa(idx), p.cfg.WSHost, OKXPublicPath, nil)
+	shard.svc = shardProcessor{pool: p, shard: idx}
+
+	p.shards = append(p.shards, shard)
+	p.topics = append(p.topics, make(map[okx.WSArgument]okx.WSSubscriptionTopic))
+
+	return shard
+}
+
+// shardOf returns index of shard topic is assigned to, must be called with mu locked
+func (p *ConnectionPool) shardOf(key okx.WSArgument) (int, bool) {
+	for idx, topics := range p.topics {
+		if _, ok := topics[key]; ok {
+			return idx, true
+		}
+	}
+
+	return 0, false
+}
+
+// updateTopicsMetric must be called with mu locked
+func (p *ConnectionPool) updateTopicsMetric() {
+	for idx, shard := range p.shards {
+		mConnectionTopics.WithLabelValues(shard.name).Set(float64(len(p.topics[idx])))
+	}
+}
+
+// assign assigns topics, which are not assigned yet, to first shards with free capacity,
+// creating new shards when all of them are full. It returns topics assigned to existing shards
+// by shard index and created shards.
+func (p *ConnectionPool) assign(topics []okx.WSSubscriptionTopic) (map[int][]okx.WSSubscriptionTopic, []*RecieverApp) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	assigned := make(map[int][]okx.WSSubscriptionTopic)
+	existing := len(p.shards)
+
+	var created []*RecieverApp
+
+	idx := 0
+
+	for _, topic := range topics {
+		key := topicKey(topic)
+
+		if _, ok := p.shardOf(key); ok {
+			continue
+		}
+
+		for idx < len(p.shards) && len(p.topics[idx]) >= p.cfg.MaxTopicsPerConnection {
+			idx++
+		}
+
+		if idx == len(p.shards) {
+			created = append(created, p.newShard())
+		}
+
+		p.topics[idx][key] = topic
+
+		if idx < existing {
+			assigned[idx] = append(assigned[idx], topic)
+		}
+	}
+
+	p.updateTopicsMetric()
+
+	return assigned, created
+}
+
+// unassign removes topics from shards and returns them by shard index
+func (p *ConnectionPool) unassign(topics []okx.WSSubscriptionTopic) map[int][]okx.WSSubscriptionTopic {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	unassigned := make(map[int][]okx.WSSubscriptionTopic)
+
+	for _, topic := range topics {
+		key := topicKey(topic)
+
+		idx, ok := p.shardOf(key)
+		if !ok {
+			continue
+		}
+
+		delete(p.topics[idx], key)
+		unassigned[idx] = append(unassigned[idx], topic)
+	}
+
+	p.updateTopicsMetric()
+
+	return unassigned
+}
+
+func (p *ConnectionPool) shard(idx int) *RecieverApp {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.shards[idx]
+}
+
+func (p *ConnectionPool) shardTopics(idx int) []okx.WSSubscriptionTopic {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	topics := make([]okx.WSSubscriptionTopic, 0, len(p.topics[idx]))
+	for _, topic := range p.topics[idx] {
+		topics = append(topics, topic)
+	}
+
+	return topics
+}
+
+// Subscribe implements core.Subscriber. Write errors are only logged, as shard subscribes to all
+// its topics on reconnect.
+func (p *ConnectionPool) Subscribe(topics ...okx.WSSubscriptionTopic) error {
+	assigned, created := p.assign(topics)
+
+	for _, shard := range created {
+		select {
+		case p.added <- shard:
+		case <-p.done:
+			return nil
+		}
+	}
+
+	for idx, shardTopics := range assigned {
+		shard := p.shard(idx)
+
+		if err := shard.subscribeBatched(shardTopics); err != nil {
+			log.Warnf("Can't subscribe on %s, will subscribe on reconnect: %s", shard.name, err.Error())
+		}
+	}
+
+	return nil
+}
+
+// Unsubscribe implements core.Subscriber
+func (p *ConnectionPool) Unsubscribe(topics ...okx.WSSubscriptionTopic) error {
+	for idx, shardTopics := range p.unassign(topics) {
+		shard := p.shard(idx)
+
+		if err := shard.Unsubscribe(shardTopics...); err != nil {
+			log.Warnf("Can't unsubscribe on %s: %s", shard.name, err.Error())
+		}
+	}
+
+	return nil
+}
+
+// Resubscribe implements core.Subscriber
+func (p *ConnectionPool) Resubscribe(topic okx.WSSubscriptionTopic) error {
+	p.mu.Lock()
+	idx, ok := p.shardOf(topicKey(topic))
+	p.mu.Unlock()
+
+	if !ok {
+		return p.Subscribe(topic)
+	}
+
+	shard := p.shard(idx)
+
+	if err := shard.Resubscribe(topic); err != nil {
+		log.Warnf("Can't resubscribe on %s, will subscribe on reconnect: %s", shard.name, err.Error())
+	}
+
+	return nil
+}
+
+func (p *ConnectionPool) Start(ctx context.Context) error {
+	grp, ctx := errgroup.WithContext(ctx)
+
+	p.mu.Lock()
+	shards := slices.Clone(p.shards)
+	p.mu.Unlock()
+
+	grp.Go(func() error {
+		defer close(p.done)
+
+		for {
+			select {
+			case shard := <-p.added:
+				log.Info("Starting new connection ", shard.name)
+
+				grp.Go(func() error {
+					return shard.Start(ctx)
+				})
+			case <-ctx.Done():
+				return nil
+			}
+		}
+	})
+
+	for i, shard := range shards {
+		if i > 0 {
+			select {
+			case <-time.After(ConnectInterval):
+			case <-ctx.Done():
+				return grp.Wait()
+			}
+		}
+
+		grp.Go(func() error {
+			return shard.Start(ctx)
+		})
+	}
+
+	return grp.Wait()
+}
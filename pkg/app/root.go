@@ -21,20 +21,24 @@ type App interface {
 }
 
 type MetricsApp struct {
-	receiver *RecieverApp
-	cfg      core.ServiceConfig
+	receivers []App
+	cfg       core.ServiceConfig
 }
 
 func New(cfg core.ServiceConfig) (App, error) {
-	var err error
-
 	app := &MetricsApp{cfg: cfg}
 
-	app.receiver, err = NewRecieverApp(&cfg.OKX)
+	pool, err := NewConnectionPool(&app.cfg.OKX)
 	if err != nil {
 		return nil, err
 	}
 
+	app.receivers = append(app.receivers, pool)
+
+	for _, account := range app.cfg.OKX.AllAccounts() {
+		app.receivers = append(app.receivers, NewPrivateRecieverApp(&app.cfg.OKX, &account))
+	}
+
 	return app, nil
 }
 
@@ -53,9 +57,11 @@ func (a *MetricsApp) Start(_ctx context.Context) error {
 		}
 	})
 
-	grp.Go(func() error {
-		return a.receiver.Start(ctx)
-	})
+	for _, receiver := range a.receivers {
+		grp.Go(func() error {
+			return receiver.Start(ctx)
+		})
+	}
 
 	grp.Go(func() error {
 		// Capacity 1, so read is not blocked when channel is empty
@@ -77,8 +83,9 @@ func (a *MetricsApp) Start(_ctx context.Context) error {
 		select {
 		case <-ctx.Done():
 			// parent context is closed, so we create a new one here
-			// ignore cancel here as it is not needed
-			ctxTimeout, _ := context.WithTimeout(context.Background(), 5*time.Second) //nolint:govet
+			ctxTimeout, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
 			return srv.Shutdown(ctxTimeout)
 		case err := <-errs:
 			return err
@@ -0,0 +1,237 @@
+package app
+
+import (
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gavt45/okx-exporter/pkg/core/domain/okx"
+	"github.com/gavt45/okx-exporter/pkg/log"
+)
+
+type subscriptionState string
+
+const (
+	subscriptionPending subscriptionState = "pending"
+	subscriptionAcked   subscriptionState = "acked"
+	subscriptionFailed  subscriptionState = "failed"
+)
+
+var subscriptionStates = []subscriptionState{subscriptionPending, subscriptionAcked, subscriptionFailed}
+
+type subscription struct {
+	topic okx.WSSubscriptionTopic
+	state subscriptionState
+	// retryAt time topic is retried at, unless it is acked
+	retryAt time.Time
+	// failures amount of consecutive failed subscriptions, retry interval is doubled after each of them
+	failures int
+}
+
+// subscriptions tracks state of topics subscribed on one connection. Subscribe requests are sent with id,
+// which okx returns in error event, so failure can be matched with topics of request.
+type subscriptions struct {
+	mu sync.Mutex
+
+	// connection name, used as connection label of metrics
+	connection string
+	lastID     int
+	// requests topics of not answered subscribe requests by request id
+	requests map[string][]okx.WSArgument
+	topics   map[okx.WSArgument]*subscription
+	// retry receives signal when some topics should be retried before next retry interval
+	retry chan struct{}
+}
+
+func newSubscriptions(connection string) *subscriptions {
+	return &subscriptions{
+		connection: connection,
+		requests:   make(map[string][]okx.WSArgument),
+		topics:     make(map[okx.WSArgument]*subscription),
+		retry:      make(chan struct{}, 1),
+	}
+}
+
+// topicKey returns argument identifying topic in okx responses
+func topicKey(topic okx.WSSubscriptionTopic) okx.WSArgument {
+	arg := topic.WSArgument
+	if topic.InstID != "" {
+		arg.InstID = topic.InstID
+	}
+
+	return arg
+}
+
+func (s *subscriptions) labels(arg okx.WSArgument, state subscriptionState) []string {
+	return []string{s.connection, string(arg.Channel), arg.InstType, arg.InstFamily, string(arg.InstID), string(state)}
+}
+
+// setState must be called with mu locked
+func (s *subscriptions) setState(arg okx.WSArgument, sub *subscription, state subscriptionState) {
+	sub.state = state
+
+	for _, st := range subscriptionStates {
+		value := 0.0
+		if st == state {
+			value = 1
+		}
+
+		mSubscriptionState.WithLabelValues(s.labels(arg, st)...).Set(value)
+	}
+}
+
+// pending marks topics pending and returns id of subscribe request for them
+func (s *subscriptions) pending(topics []okx.WSSubscriptionTopic) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastID++
+	id := strconv.Itoa(s.lastID)
+
+	args := make([]okx.WSArgument, 0, len(topics))
+
+	for _, topic := range topics {
+		arg := topicKey(topic)
+
+		sub, ok := s.topics[arg]
+		if !ok {
+			sub = &subscription{topic: topic}
+			s.topics[arg] = sub
+		}
+
+		// not acknowledged subscription is retried after retry interval
+		sub.retryAt = time.Now().Add(SubscriptionRetryInterval)
+		s.setState(arg, sub, subscriptionPending)
+		args = append(args, arg)
+	}
+
+	s.requests[id] = args
+
+	return id
+}
+
+// remove forgets topics, i.e. after unsubscribe
+func (s *subscriptions) remove(topics []okx.WSSubscriptionTopic) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, topic := range topics {
+		s.forget(topicKey(topic))
+	}
+}
+
+// reset forgets all topics and requests, i.e. before subscribing on new connection
+func (s *subscriptions) reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for arg := range s.topics {
+		s.forget(arg)
+	}
+
+	s.requests = make(map[string][]okx.WSArgument)
+}
+
+// forget deletes topic and its metrics, must be called with mu locked
+func (s *subscriptions) forget(arg okx.WSArgument) {
+	delete(s.topics, arg)
+
+	for _, st := range subscriptionStates {
+		mSubscriptionState.DeleteLabelValues(s.labels(arg, st)...)
+	}
+}
+
+// retryBackoff returns delay before retry of topic failed given amount of times in a row
+func retryBackoff(failures int) time.Duration {
+	backoff := SubscriptionRetryInterval
+
+	for i := 1; i < failures && backoff < SubscriptionMaxRetryInterval; i++ {
+		backoff *= 2
+	}
+
+	return min(backoff, SubscriptionMaxRetryInterval)
+}
+
+// handleEvent updates state of topics from subscribe, unsubscribe or error event.
+// Subscribe events ack single topic, error events carry only request id, so error is attributed to topic
+// only when it is the last not acknowledged topic of request. Otherwise topics of request are retried
+// right away, one per request, so error of retry can be attributed.
+func (s *subscriptions) handleEvent(msg okx.WSData) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch msg.Event { //nolint:exhaustive // other events are not related to subscriptions
+	case okx.OperationSubscribe:
+		if sub, ok := s.topics[msg.Arg]; ok {
+			sub.failures = 0
+			s.setState(msg.Arg, sub, subscriptionAcked)
+		}
+
+		s.ack(msg.ID, msg.Arg)
+	case okx.OperationError:
+		args := s.requests[msg.ID]
+		delete(s.requests, msg.ID)
+
+		if len(args) != 1 {
+			log.Warnf("Got okx error on %s for %d topics with code %s: %s", s.connection, len(args), msg.Code, msg.Msg)
+			s.retryNow(args)
+
+			return
+		}
+
+		if sub, ok := s.topics[args[0]]; ok {
+			sub.failures++
+			sub.retryAt = time.Now().Add(retryBackoff(sub.failures))
+
+			log.Warnf("Subscription to %v on %s failed %d times with code %s: %s, retrying at %s",
+				args[0], s.connection, sub.failures, msg.Code, msg.Msg, sub.retryAt.Format(time.RFC3339))
+			s.setState(args[0], sub, subscriptionFailed)
+		}
+	default:
+		log.Debug("Got event: ", msg)
+	}
+}
+
+// retryNow makes pending topics retryable and signals retrier, must be called with mu locked
+func (s *subscriptions) retryNow(args []okx.WSArgument) {
+	for _, arg := range args {
+		if sub, ok := s.topics[arg]; ok && sub.state == subscriptionPending {
+			sub.retryAt = time.Now()
+		}
+	}
+
+	select {
+	case s.retry <- struct{}{}:
+	default:
+	}
+}
+
+// ack removes acknowledged topic from request, request is forgotten when all its topics are acknowledged.
+// Must be called with mu locked.
+func (s *subscriptions) ack(id string, arg okx.WSArgument) {
+	args := slices.DeleteFunc(s.requests[id], func(a okx.WSArgument) bool { return a == arg })
+
+	if len(args) == 0 {
+		delete(s.requests, id)
+		return
+	}
+
+	s.requests[id] = args
+}
+
+// retryable returns not acknowledged topics, which retry time has come
+func (s *subscriptions) retryable(now time.Time) []okx.WSSubscriptionTopic {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var topics []okx.WSSubscriptionTopic
+
+	for _, sub := range s.topics {
+		if sub.state != subscriptionAcked && !now.Before(sub.retryAt) {
+			topics = append(topics, sub.topic)
+		}
+	}
+
+	return topics
+}
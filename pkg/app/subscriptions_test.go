@@ -0,0 +1,153 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gavt45/okx-exporter/pkg/core/domain/okx"
+)
+
+var (
+	testTickers = okx.WSSubscriptionTopic{
+		WSArgument: okx.WSArgument{Channel: okx.ChannelTickers},
+		InstID:     okx.InstrumentETHxUSDT,
+	}
+	testBogus = okx.WSSubscriptionTopic{
+		WSArgument: okx.WSArgument{Channel: "bogus"},
+		InstID:     okx.InstrumentETHxUSDT,
+	}
+)
+
+func subscribeEvent(id string, topic okx.WSSubscriptionTopic) okx.WSData {
+	return okx.WSData{ID: id, Event: okx.OperationSubscribe, Arg: topicKey(topic)}
+}
+
+func errorEvent(id string) okx.WSData {
+	return okx.WSData{ID: id, Event: okx.OperationError, Code: "60018", Msg: "Wrong URL or channel"}
+}
+
+func assertState(t *testing.T, subs *subscriptions, topic okx.WSSubscriptionTopic, want subscriptionState) {
+	t.Helper()
+
+	sub, ok := subs.topics[topicKey(topic)]
+	if !ok {
+		t.Fatalf("topic %v is not tracked", topic)
+	}
+
+	if sub.state != want {
+		t.Errorf("topic %v is %s, want %s", topic.Channel, sub.state, want)
+	}
+}
+
+func TestSubscriptionsAckThenError(t *testing.T) {
+	subs := newSubscriptions("test")
+
+	id := subs.pending([]okx.WSSubscriptionTopic{testTickers, testBogus})
+
+	subs.handleEvent(subscribeEvent(id, testTickers))
+	subs.handleEvent(errorEvent(id))
+
+	assertState(t, subs, testTickers, subscriptionAcked)
+	assertState(t, subs, testBogus, subscriptionFailed)
+
+	if len(subs.requests) != 0 {
+		t.Errorf("requests are not forgotten: %v", subs.requests)
+	}
+}
+
+func TestSubscriptionsErrorThenAck(t *testing.T) {
+	subs := newSubscriptions("test")
+
+	id := subs.pending([]okx.WSSubscriptionTopic{testTickers, testBogus})
+
+	subs.handleEvent(errorEvent(id))
+
+	// Error can't be attributed to one of topics, so both are left pending and retrier is signalled
+	assertState(t, subs, testTickers, subscriptionPending)
+	assertState(t, subs, testBogus, subscriptionPending)
+
+	select {
+	case <-subs.retry:
+	default:
+		t.Error("retrier is not signalled")
+	}
+
+	subs.handleEvent(subscribeEvent(id, testTickers))
+
+	assertState(t, subs, testTickers, subscriptionAcked)
+
+	retry := subs.retryable(time.Now())
+	if len(retry) != 1 || retry[0] != testBogus {
+		t.Fatalf("got retryable %v, want bogus topic", retry)
+	}
+
+	// Retried topic is subscribed alone, so error is attributed to it
+	retryID := subs.pending(retry)
+	subs.handleEvent(errorEvent(retryID))
+
+	assertState(t, subs, testBogus, subscriptionFailed)
+}
+
+func TestSubscriptionsAllAcked(t *testing.T) {
+	subs := newSubscriptions("test")
+
+	candle := okx.WSSubscriptionTopic{
+		WSArgument: okx.WSArgument{Channel: okx.ChannelCandle1H},
+		InstID:     okx.InstrumentETHxUSDT,
+	}
+
+	id := subs.pending([]okx.WSSubscriptionTopic{testTickers, candle})
+
+	subs.handleEvent(subscribeEvent(id, candle))
+	subs.handleEvent(subscribeEvent(id, testTickers))
+
+	assertState(t, subs, testTickers, subscriptionAcked)
+	assertState(t, subs, candle, subscriptionAcked)
+
+	if len(subs.requests) != 0 {
+		t.Errorf("requests are not forgotten: %v", subs.requests)
+	}
+
+	if retry := subs.retryable(time.Now().Add(time.Hour)); len(retry) != 0 {
+		t.Errorf("acked topics are retryable: %v", retry)
+	}
+}
+
+func TestSubscriptionsFailureBackoff(t *testing.T) {
+	subs := newSubscriptions("test")
+
+	for failures := 1; failures <= 3; failures++ {
+		id := subs.pending([]okx.WSSubscriptionTopic{testBogus})
+		subs.handleEvent(errorEvent(id))
+
+		assertState(t, subs, testBogus, subscriptionFailed)
+
+		backoff := retryBackoff(failures)
+		if want := SubscriptionRetryInterval << (failures - 1); backoff != want {
+			t.Errorf("got backoff %s after %d failures, want %s", backoff, failures, want)
+		}
+
+		if retry := subs.retryable(time.Now().Add(backoff / 2)); len(retry) != 0 {
+			t.Errorf("failed topic is retried before backoff: %v", retry)
+		}
+
+		if retry := subs.retryable(time.Now().Add(backoff + time.Second)); len(retry) != 1 {
+			t.Errorf("failed topic is not retried after backoff: %v", retry)
+		}
+	}
+
+	if backoff := retryBackoff(100); backoff != SubscriptionMaxRetryInterval {
+		t.Errorf("got backoff %s, want max %s", backoff, SubscriptionMaxRetryInterval)
+	}
+}
+
+func TestSubscriptionsRemove(t *testing.T) {
+	subs := newSubscriptions("test")
+
+	subs.pending([]okx.WSSubscriptionTopic{testTickers})
+	subs.remove([]okx.WSSubscriptionTopic{testTickers})
+
+	if len(subs.topics) != 0 {
+		t.Errorf("topic is not removed: %v", subs.topics)
+	}
+}
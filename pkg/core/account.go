@@ -0,0 +1,183 @@
+package core
+
+import (
+	"encoding/json"
+
+	"github.com/gavt45/okx-exporter/pkg/core/domain/okx"
+	"github.com/gavt45/okx-exporter/pkg/log"
+)
+
+// List of private topics subscribed by account service
+var AccountTopics = []okx.WSSubscriptionTopic{
+	{
+		WSArgument: okx.WSArgument{
+			Channel: okx.ChannelAccount,
+		},
+	},
+	{
+		WSArgument: okx.WSArgument{
+			Channel:  okx.ChannelPositions,
+			InstType: okx.InstTypeAny,
+		},
+	},
+	{
+		WSArgument: okx.WSArgument{
+			Channel:  okx.ChannelOrders,
+			InstType: okx.InstTypeAny,
+		},
+	},
+}
+
+// orderState last seen state of not final order
+type orderState struct {
+	state     okx.OrderState
+	accFillSz float64
+	fee       float64
+}
+
+// AccountService processes private channels of okx account
+type AccountService struct {
+	// account name, used as account label of metrics
+	account string
+
+	// orders states of not final orders by order id
+	orders map[string]*orderState
+}
+
+func NewAccountService(account string) *AccountService {
+	return &AccountService{
+		account: account,
+		orders:  make(map[string]*orderState),
+	}
+}
+
+// Topics returns private topics service needs
+func (s *AccountService) Topics() []okx.WSSubscriptionTopic {
+	return AccountTopics
+}
+
+func (s *AccountService) ProcessMessage(data okx.WSData) error {
+	if data.Event != okx.OperationEmpty {
+		return nil // don't process callbacks
+	}
+
+	// Every private channel sends list of items, it is empty i.e. when there are no open positions
+	switch data.Arg.Channel { //nolint:exhaustive // only private channels are received here
+	case okx.ChannelAccount:
+		for _, accountData := range data.Data {
+			account := okx.WSDataAccount{}
+
+			if err := json.Unmarshal(accountData, &account); err != nil {
+				return parseError(data, err, "account")
+			}
+
+			log.Debug("Got account data: ", account)
+
+			mAccountTotalEquity.WithLabelValues(s.account).Set(float64(account.TotalEq))
+			mAccountMarginRatio.WithLabelValues(s.account).Set(float64(account.MgnRatio))
+
+			for _, detail := range account.Details {
+				mAccountEquity.WithLabelValues(s.account, detail.Ccy).Set(float64(detail.Eq))
+				mAccountAvailableBalance.WithLabelValues(s.account, detail.Ccy).Set(float64(detail.AvailBal))
+				mAccountFrozenBalance.WithLabelValues(s.account, detail.Ccy).Set(float64(detail.FrozenBal))
+				mAccountEquityUsd.WithLabelValues(s.account, detail.Ccy).Set(float64(detail.EqUsd))
+			}
+		}
+	case okx.ChannelPositions:
+		for _, positionData := range data.Data {
+			position := okx.WSDataPosition{}
+
+			if err := json.Unmarshal(positionData, &position); err != nil {
+				return parseError(data, err, "positions")
+			}
+
+			log.Debug("Got position data: ", position)
+
+			s.exportPosition(position)
+		}
+	case okx.ChannelOrders:
+		for _, orderData := range data.Data {
+			order := okx.WSDataOrder{}
+
+			if err := json.Unmarshal(orderData, &order); err != nil {
+				return parseError(data, err, "orders")
+			}
+
+			log.Debug("Got order data: ", order)
+
+			s.exportOrder(order)
+		}
+	default:
+		log.Warn("Unknown private channel: " + data.Arg.Channel)
+	}
+
+	return nil
+}
+
+// exportPosition sets position metrics, deleting them when position is closed
+func (s *AccountService) exportPosition(position okx.WSDataPosition) {
+	labels := []string{s.account, string(position.InstID), position.PosSide}
+
+	if position.Pos == 0 {
+		for _, vec := range positionVecs {
+			vec.DeleteLabelValues(labels...)
+		}
+
+		return
+	}
+
+	mPositionSize.WithLabelValues(labels...).Set(float64(position.Pos))
+	mPositionAvgPrice.WithLabelValues(labels...).Set(float64(position.AvgPx))
+	mPositionUpl.WithLabelValues(labels...).Set(float64(position.Upl))
+	mPositionMarginRatio.WithLabelValues(labels...).Set(float64(position.MgnRatio))
+	mPositionLeverage.WithLabelValues(labels...).Set(float64(position.Lever))
+	mPositionLiquidationPrice.WithLabelValues(labels...).Set(float64(position.LiqPx))
+
+	if distance, ok := position.LiquidationDistancePercent(); ok {
+		mPositionLiquidationDistance.WithLabelValues(labels...).Set(distance)
+	} else {
+		mPositionLiquidationDistance.DeleteLabelValues(labels...)
+	}
+}
+
+// exportOrder counts order state transition, filled volume and fee paid since previous update of order
+func (s *AccountService) exportOrder(order okx.WSDataOrder) {
+	account, instID := s.account, string(order.InstID)
+
+	prev, ok := s.orders[order.OrdID]
+	if !ok {
+		// Order wasn't seen before, i.e. after restart or reconnect, so its earlier fills are unknown
+		// and only fill of this update is counted
+		prev = &orderState{
+			accFillSz: float64(order.AccFillSz - order.FillSz),
+			fee:       float64(order.Fee - order.FillFee),
+		}
+		s.orders[order.OrdID] = prev
+	}
+
+	if order.State != prev.state {
+		mOrders.WithLabelValues(account, instID, order.OrdType, string(order.State)).Inc()
+	}
+
+	if filled := float64(order.AccFillSz) - prev.accFillSz; filled > 0 {
+		mOrdersFilledVolume.WithLabelValues(account, instID, string(order.Side)).Add(filled)
+
+		// FillTime is time of latest fill, so latency is observed only when it is the first one
+		if prev.accFillSz == 0 {
+			mOrderFirstFill.WithLabelValues(account, instID, order.OrdType).
+				Observe(order.FillTime.Sub(order.CTime.Time).Seconds())
+		}
+	}
+
+	// fee is negative when charged
+	if paid := prev.fee - float64(order.Fee); paid > 0 {
+		mOrdersFees.WithLabelValues(account, instID, order.FeeCcy).Add(paid)
+	}
+
+	if order.State.Final() {
+		delete(s.orders, order.OrdID)
+		return
+	}
+
+	prev.state, prev.accFillSz, prev.fee = order.State, float64(order.AccFillSz), float64(order.Fee)
+}
@@ -0,0 +1,189 @@
+package core
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+
+	"github.com/gavt45/okx-exporter/pkg/core/domain/okx"
+	"github.com/gavt45/okx-exporter/pkg/log"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const bpsInUnit = 10000
+
+type bookKey struct {
+	channel    okx.Channel
+	instrument okx.Instrument
+}
+
+// orderBook local order book of one instrument, levels are keyed by price string
+type orderBook struct {
+	bids map[string]okx.BookLevel
+	asks map[string]okx.BookLevel
+	// resyncing is set after resubscribe, updates are ignored until snapshot is received
+	resyncing bool
+}
+
+func newOrderBook() *orderBook {
+	return &orderBook{
+		bids: make(map[string]okx.BookLevel),
+		asks: make(map[string]okx.BookLevel),
+	}
+}
+
+func applyLevels(side map[string]okx.BookLevel, levels []okx.BookLevel) {
+	for _, level := range levels {
+		if level.Size == 0 {
+			delete(side, level.Px)
+			continue
+		}
+
+		side[level.Px] = level
+	}
+}
+
+func (b *orderBook) apply(data okx.WSDataBook) {
+	applyLevels(b.bids, data.Bids)
+	applyLevels(b.asks, data.Asks)
+}
+
+// sortedBids returns bids sorted by price descending
+func (b *orderBook) sortedBids() []okx.BookLevel {
+	bids := make([]okx.BookLevel, 0, len(b.bids))
+	for _, level := range b.bids {
+		bids = append(bids, level)
+	}
+
+	sort.Slice(bids, func(i, j int) bool { return bids[i].Price > bids[j].Price })
+
+	return bids
+}
+
+// sortedAsks returns asks sorted by price ascending
+func (b *orderBook) sortedAsks() []okx.BookLevel {
+	asks := make([]okx.BookLevel, 0, len(b.asks))
+	for _, level := range b.asks {
+		asks = append(asks, level)
+	}
+
+	sort.Slice(asks, func(i, j int) bool { return asks[i].Price < asks[j].Price })
+
+	return asks
+}
+
+// depth returns summary size of levels with price not worse than limit
+func depth(levels []okx.BookLevel, within func(price float64) bool) float64 {
+	var size float64
+
+	for _, level := range levels {
+		if !within(level.Price) {
+			break
+		}
+
+		size += level.Size
+	}
+
+	return size
+}
+
+func (s *Service) resubscribeBook(key bookKey, reason string) error {
+	log.Warnf("Resubscribing to %s for %s: %s", key.channel, key.instrument, reason)
+
+	book := newOrderBook()
+	book.resyncing = true
+	s.books[key] = book
+
+	err := s.sub.Resubscribe(okx.WSSubscriptionTopic{
+		WSArgument: okx.WSArgument{
+			Channel: key.channel,
+		},
+		InstID: key.instrument,
+	})
+	if err != nil {
+		return errors.Wrap(err, "can't resubscribe to "+string(key.channel))
+	}
+
+	return nil
+}
+
+func (s *Service) processBook(data okx.WSData) error {
+	key := bookKey{channel: data.Arg.Channel, instrument: data.Arg.InstID}
+
+	for _, bookData := range data.Data {
+		update := okx.WSDataBook{}
+
+		if err := json.Unmarshal(bookData, &update); err != nil {
+			return parseError(data, err, "book")
+		}
+
+		book, ok := s.books[key]
+
+		switch {
+		case !key.channel.IsIncrementalBook() || data.Action == okx.ActionSnapshot:
+			book = newOrderBook()
+			s.books[key] = book
+		case !ok:
+			return s.resubscribeBook(key, "got update without snapshot")
+		case book.resyncing:
+			log.Debugf("Skipping %s update for %s until snapshot", key.channel, key.instrument)
+			continue
+		}
+
+		book.apply(update)
+
+		bids, asks := book.sortedBids(), book.sortedAsks()
+
+		if key.channel.IsIncrementalBook() {
+			if checksum := okx.BookChecksum(bids, asks); checksum != update.Checksum {
+				return s.resubscribeBook(key, "checksum mismatch")
+			}
+		}
+
+		s.exportBook(key, bids, asks)
+	}
+
+	return nil
+}
+
+// exportBook exports best prices, spread and depth. Series of empty side are deleted, as well as spread
+// and depth, which need both sides.
+func (s *Service) exportBook(key bookKey, bids, asks []okx.BookLevel) {
+	instID, channel := string(key.instrument), string(key.channel)
+
+	if len(bids) > 0 {
+		mBookBestBid.WithLabelValues(instID, channel).Set(bids[0].Price)
+	} else {
+		mBookBestBid.DeleteLabelValues(instID, channel)
+	}
+
+	if len(asks) > 0 {
+		mBookBestAsk.WithLabelValues(instID, channel).Set(asks[0].Price)
+	} else {
+		mBookBestAsk.DeleteLabelValues(instID, channel)
+	}
+
+	if len(bids) == 0 || len(asks) == 0 {
+		mBookSpread.DeleteLabelValues(instID, channel)
+		mBookDepth.DeletePartialMatch(prometheus.Labels{"instrument": instID, "channel": channel})
+
+		return
+	}
+
+	bestBid, bestAsk := bids[0].Price, asks[0].Price
+	mid := (bestBid + bestAsk) / 2
+
+	mBookSpread.WithLabelValues(instID, channel).Set(bestAsk - bestBid)
+
+	for _, bps := range s.cfg.BookDepthBps {
+		distance := mid * float64(bps) / bpsInUnit
+		label := strconv.Itoa(bps)
+
+		bidDepth := depth(bids, func(price float64) bool { return price >= mid-distance })
+		askDepth := depth(asks, func(price float64) bool { return price <= mid+distance })
+
+		mBookDepth.WithLabelValues(instID, channel, string(okx.SideBuy), label).Set(bidDepth)
+		mBookDepth.WithLabelValues(instID, channel, string(okx.SideSell), label).Set(askDepth)
+	}
+}
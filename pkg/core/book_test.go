@@ -0,0 +1,208 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/gavt45/okx-exporter/pkg/core/domain/okx"
+)
+
+type fakeSubscriber struct {
+	resubscribed []okx.WSSubscriptionTopic
+}
+
+func (f *fakeSubscriber) Subscribe(...okx.WSSubscriptionTopic) error {
+	return nil
+}
+
+func (f *fakeSubscriber) Unsubscribe(...okx.WSSubscriptionTopic) error {
+	return nil
+}
+
+func (f *fakeSubscriber) Resubscribe(topic okx.WSSubscriptionTopic) error {
+	f.resubscribed = append(f.resubscribed, topic)
+	return nil
+}
+
+func newTestService(t *testing.T) (*Service, *fakeSubscriber) {
+	t.Helper()
+
+	sub := &fakeSubscriber{}
+
+	svc, err := NewService(&OKXConfig{BookDepthBps: []int{10}}, sub)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return svc, sub
+}
+
+func levelsJSON(levels [][2]string) string {
+	parts := make([]string, 0, len(levels))
+	for _, level := range levels {
+		parts = append(parts, fmt.Sprintf(`["%s","%s","0","1"]`, level[0], level[1]))
+	}
+
+	return "[" + strings.Join(parts, ",") + "]"
+}
+
+func toBookLevels(levels [][2]string) []okx.BookLevel {
+	result := make([]okx.BookLevel, 0, len(levels))
+
+	for _, level := range levels {
+		if level[1] != "0" {
+			result = append(result, okx.BookLevel{Px: level[0], Sz: level[1]})
+		}
+	}
+
+	return result
+}
+
+// bookMessage returns books message with bids and asks, checksum is calculated from expected book levels
+func bookMessage(action okx.Action, bids, asks, wantBids, wantAsks [][2]string) okx.WSData {
+	checksum := okx.BookChecksum(toBookLevels(wantBids), toBookLevels(wantAsks))
+	data := fmt.Sprintf(`{"bids":%s,"asks":%s,"ts":"1597026383085","checksum":%d}`,
+		levelsJSON(bids), levelsJSON(asks), checksum)
+
+	return okx.WSData{
+		Action: action,
+		Arg:    okx.WSArgument{Channel: okx.ChannelBooks, InstID: okx.InstrumentETHxUSDT},
+		Data:   []json.RawMessage{json.RawMessage(data)},
+	}
+}
+
+var testBookKey = bookKey{channel: okx.ChannelBooks, instrument: okx.InstrumentETHxUSDT}
+
+func TestProcessBookSnapshotAndUpdate(t *testing.T) {
+	svc, sub := newTestService(t)
+
+	bids := [][2]string{{"100", "1"}, {"99", "2"}}
+	asks := [][2]string{{"101", "3"}, {"102", "4"}}
+
+	if err := svc.processBook(bookMessage(okx.ActionSnapshot, bids, asks, bids, asks)); err != nil {
+		t.Fatal(err)
+	}
+
+	// Update changes size of 100 bid, deletes 102 ask and adds 100.5 ask
+	err := svc.processBook(bookMessage(okx.ActionUpdate,
+		[][2]string{{"100", "5"}},
+		[][2]string{{"102", "0"}, {"100.5", "1"}},
+		[][2]string{{"100", "5"}, {"99", "2"}},
+		[][2]string{{"100.5", "1"}, {"101", "3"}},
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(sub.resubscribed) != 0 {
+		t.Fatalf("unexpected resubscribes %v", sub.resubscribed)
+	}
+
+	book := svc.books[testBookKey]
+
+	gotBids, gotAsks := book.sortedBids(), book.sortedAsks()
+	if len(gotBids) != 2 || gotBids[0].Sz != "5" || gotBids[1].Px != "99" {
+		t.Errorf("unexpected bids %+v", gotBids)
+	}
+
+	if len(gotAsks) != 2 || gotAsks[0].Px != "100.5" || gotAsks[1].Px != "101" {
+		t.Errorf("unexpected asks %+v", gotAsks)
+	}
+}
+
+func TestProcessBookResyncsOnce(t *testing.T) {
+	svc, sub := newTestService(t)
+
+	bids := [][2]string{{"100", "1"}}
+	asks := [][2]string{{"101", "1"}}
+
+	if err := svc.processBook(bookMessage(okx.ActionSnapshot, bids, asks, bids, asks)); err != nil {
+		t.Fatal(err)
+	}
+
+	// Checksum of update doesn't match local book
+	mismatch := bookMessage(okx.ActionUpdate, [][2]string{{"100", "2"}}, nil, [][2]string{{"100", "3"}}, asks)
+
+	if err := svc.processBook(mismatch); err != nil {
+		t.Fatal(err)
+	}
+
+	// Updates still in flight are ignored until snapshot
+	for i := 0; i < 5; i++ {
+		update := bookMessage(okx.ActionUpdate, [][2]string{{"100", "2"}}, nil, [][2]string{{"100", "2"}}, asks)
+
+		if err := svc.processBook(update); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(sub.resubscribed) != 1 {
+		t.Fatalf("got %d resubscribes, want 1", len(sub.resubscribed))
+	}
+
+	if topic := sub.resubscribed[0]; topic.Channel != okx.ChannelBooks || topic.InstID != okx.InstrumentETHxUSDT {
+		t.Errorf("unexpected resubscribe topic %+v", topic)
+	}
+
+	if err := svc.processBook(bookMessage(okx.ActionSnapshot, bids, asks, bids, asks)); err != nil {
+		t.Fatal(err)
+	}
+
+	if book := svc.books[testBookKey]; book.resyncing {
+		t.Error("book is still resyncing after snapshot")
+	}
+}
+
+func TestProcessBookUpdateWithoutSnapshot(t *testing.T) {
+	svc, sub := newTestService(t)
+
+	update := bookMessage(okx.ActionUpdate, [][2]string{{"100", "1"}}, nil, [][2]string{{"100", "1"}}, nil)
+
+	for i := 0; i < 3; i++ {
+		if err := svc.processBook(update); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(sub.resubscribed) != 1 {
+		t.Errorf("got %d resubscribes, want 1", len(sub.resubscribed))
+	}
+}
+
+func TestExportBookDeletesEmptySide(t *testing.T) {
+	svc, _ := newTestService(t)
+
+	bids := [][2]string{{"100", "1"}}
+	asks := [][2]string{{"101", "1"}}
+
+	if err := svc.processBook(bookMessage(okx.ActionSnapshot, bids, asks, bids, asks)); err != nil {
+		t.Fatal(err)
+	}
+
+	// Ask side becomes empty
+	err := svc.processBook(bookMessage(okx.ActionUpdate, nil, [][2]string{{"101", "0"}}, bids, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	instID, channel := string(okx.InstrumentETHxUSDT), string(okx.ChannelBooks)
+
+	// DeleteLabelValues reports whether series existed
+	if !mBookBestBid.DeleteLabelValues(instID, channel) {
+		t.Error("best bid is deleted")
+	}
+
+	if mBookBestAsk.DeleteLabelValues(instID, channel) {
+		t.Error("best ask of empty side is not deleted")
+	}
+
+	if mBookSpread.DeleteLabelValues(instID, channel) {
+		t.Error("spread is not deleted")
+	}
+
+	if mBookDepth.DeleteLabelValues(instID, channel, string(okx.SideBuy), "10") {
+		t.Error("depth is not deleted")
+	}
+}
@@ -1,7 +1,102 @@
 package core
 
+import (
+	"path"
+	"regexp"
+	"time"
+
+	"github.com/gavt45/okx-exporter/pkg/core/domain/okx"
+	validator "github.com/go-playground/validator/v10"
+)
+
+// TopicConfig subscription not bound to configured instruments, i.e. liquidation-orders by instType
+type TopicConfig struct {
+	Channel    okx.Channel `json:"channel" yaml:"channel" validate:"required,okx_public_channel"`
+	InstType   string      `json:"inst_type" yaml:"inst_type"`
+	InstFamily string      `json:"inst_family" yaml:"inst_family"`
+}
+
+// DiscoveryConfig filters of instruments subscribed automatically as they are listed in instruments channel
+type DiscoveryConfig struct {
+	Enabled bool `json:"enabled" yaml:"enabled" config:"discovery_enabled"`
+	// InstTypes instruments channel is subscribed for each of them, i.e. SPOT, SWAP
+	InstTypes []string `json:"inst_types" yaml:"inst_types" config:"discovery_inst_types" validate:"required_if=Enabled true"` //nolint:lll
+	// QuoteCcys quote currencies of discovered instruments, any if empty
+	QuoteCcys []string `json:"quote_ccys" yaml:"quote_ccys" config:"discovery_quote_ccys"`
+	// InstIDRegex regular expression discovered instrument ids must match, any if empty
+	InstIDRegex string `json:"inst_id_regex" yaml:"inst_id_regex" config:"discovery_inst_id_regex" validate:"omitempty,okx_regex"` //nolint:lll
+}
+
+// ReconnectConfig reconnect delay starts from initial interval and doubles after every failed attempt
+// up to max interval, actual delay is randomized in [delay/2, delay)
+type ReconnectConfig struct {
+	InitialInterval time.Duration `json:"initial_interval" yaml:"initial_interval" config:"reconnect_initial_interval" validate:"gt=0"`         //nolint:lll
+	MaxInterval     time.Duration `json:"max_interval" yaml:"max_interval" config:"reconnect_max_interval" validate:"gtefield=InitialInterval"` //nolint:lll
+}
+
+// Credentials okx API key, used to login on private endpoint.
+// Values can be read from files, i.e. kubernetes secret mounts, file values take precedence.
+type Credentials struct {
+	APIKey         string `json:"api_key" yaml:"api_key" config:"api_key"`
+	APIKeyFile     string `json:"api_key_file" yaml:"api_key_file" config:"api_key_file"`
+	SecretKey      Secret `json:"secret_key" yaml:"secret_key" config:"secret_key" validate:"required_with=APIKey"`
+	SecretKeyFile  string `json:"secret_key_file" yaml:"secret_key_file" config:"secret_key_file"`
+	Passphrase     Secret `json:"passphrase" yaml:"passphrase" config:"passphrase" validate:"required_with=APIKey"`
+	PassphraseFile string `json:"passphrase_file" yaml:"passphrase_file" config:"passphrase_file"`
+}
+
+// Empty reports whether API key is not configured
+func (c Credentials) Empty() bool {
+	return c.APIKey == ""
+}
+
+// DefaultAccount name of account configured with top level credentials
+const DefaultAccount = "default"
+
+// AccountConfig okx account or sub-account, Name is used as account label of private metrics
+type AccountConfig struct {
+	Name        string `json:"name" yaml:"name" validate:"required"`
+	Credentials `yaml:",inline"`
+}
+
+// Keepalive modes: okx text "ping" message, websocket ping control message or both of them
+const (
+	KeepaliveText    = "text"
+	KeepaliveControl = "control"
+	KeepaliveBoth    = "both"
+)
+
 type OKXConfig struct {
-	WSHost string `json:"ws_host" yaml:"ws_host" config:"ws_host"`
+	WSHost        string `json:"ws_host" yaml:"ws_host" config:"ws_host"`
+	PrivateWSHost string `json:"private_ws_host" yaml:"private_ws_host" config:"private_ws_host"`
+	KeepaliveMode string `json:"keepalive_mode" yaml:"keepalive_mode" config:"keepalive_mode" validate:"oneof=text control both"` //nolint:lll
+	// MaxTopicsPerConnection public topics are distributed across connections with at most this amount of topics each
+	MaxTopicsPerConnection int `json:"max_topics_per_connection" yaml:"max_topics_per_connection" config:"max_topics_per_connection" validate:"gt=0"` //nolint:lll
+	// Credentials of DefaultAccount, private endpoint connection is started for it only when they are set
+	Credentials `yaml:",inline"`
+	// Accounts private endpoint connection is started for each of them. Can be set only from file.
+	Accounts []AccountConfig `json:"accounts" yaml:"accounts" config:"-" validate:"unique=Name,dive"`
+	// Instruments fixed list of instruments, ignored when discovery is enabled
+	Instruments []okx.Instrument `json:"instruments" yaml:"instruments" config:"instruments" validate:"required_unless=Discovery.Enabled true,dive,okx_instid"` //nolint:lll
+	// Channels maps instrument id or glob (i.e. *-USDT) to channels subscribed for matching instruments.
+	// Instruments not matched by any key are subscribed to DefaultChannels. Private channels are not allowed,
+	// as public connections are not logged in. Can be set only from file.
+	Channels map[string][]okx.Channel `json:"channels" yaml:"channels" config:"-" validate:"dive,keys,okx_instglob,endkeys,required,dive,okx_public_channel"` //nolint:lll
+	// BookDepthBps distances from mid price in basis points, order book depth is exported within each of them
+	BookDepthBps []int `json:"book_depth_bps" yaml:"book_depth_bps" config:"book_depth_bps" validate:"dive,gt=0"`
+	// Topics subscribed in addition to instrument channels. Can be set only from file.
+	Topics    []TopicConfig   `json:"topics" yaml:"topics" config:"-" validate:"dive"`
+	Discovery DiscoveryConfig `json:"discovery" yaml:"discovery" config:"discovery"`
+	Reconnect ReconnectConfig `json:"reconnect" yaml:"reconnect" config:"reconnect"`
+}
+
+// AllAccounts returns configured accounts, including DefaultAccount when top level credentials are set
+func (c *OKXConfig) AllAccounts() []AccountConfig {
+	if c.Credentials.Empty() {
+		return c.Accounts
+	}
+
+	return append([]AccountConfig{{Name: DefaultAccount, Credentials: c.Credentials}}, c.Accounts...)
 }
 
 type ServiceConfig struct {
@@ -9,3 +104,33 @@ type ServiceConfig struct {
 	Port int       `json:"port" yaml:"port" config:"port" validate:"required"`
 	OKX  OKXConfig `json:"okx" yaml:"okx" config:"okx"`
 }
+
+// RegisterValidations registers custom config validation tags
+func RegisterValidations(v *validator.Validate) error {
+	err := v.RegisterValidation("okx_instid", func(fl validator.FieldLevel) bool {
+		return okx.Instrument(fl.Field().String()).Valid()
+	})
+	if err != nil {
+		return err
+	}
+
+	err = v.RegisterValidation("okx_public_channel", func(fl validator.FieldLevel) bool {
+		return !okx.Channel(fl.Field().String()).RequiresLogin()
+	})
+	if err != nil {
+		return err
+	}
+
+	err = v.RegisterValidation("okx_instglob", func(fl validator.FieldLevel) bool {
+		_, err := path.Match(fl.Field().String(), "")
+		return err == nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return v.RegisterValidation("okx_regex", func(fl validator.FieldLevel) bool {
+		_, err := regexp.Compile(fl.Field().String())
+		return err == nil
+	})
+}
@@ -0,0 +1,97 @@
+package okx
+
+import (
+	"encoding/json"
+	"errors"
+	"hash/crc32"
+	"strconv"
+	"strings"
+)
+
+// Amount of best levels of each side used in order book checksum
+const ChecksumDepth = 25
+
+var bookChannels = map[Channel]bool{
+	ChannelBooks:  true,
+	ChannelBooks5: true,
+	ChannelBBOTbt: true,
+}
+
+// Channels sending snapshot first and incremental updates after it
+var incrementalBookChannels = map[Channel]bool{
+	ChannelBooks: true,
+}
+
+// IsBook reports whether channel is one of order book channels
+func (c Channel) IsBook() bool {
+	return bookChannels[c]
+}
+
+// IsIncrementalBook reports whether order book channel sends incremental updates with checksum,
+// other book channels send full book in every message
+func (c Channel) IsIncrementalBook() bool {
+	return incrementalBookChannels[c]
+}
+
+// BookLevel order book price level. Price and size are kept as strings too, as they are used in checksum.
+type BookLevel struct {
+	Px    string
+	Sz    string
+	Price float64
+	Size  float64
+}
+
+var ErrShortBookLevelArray error = errors.New("book level array must have at least 2 values")
+
+func (l *BookLevel) UnmarshalJSON(data []byte) error {
+	var arr []string
+
+	// Example: ["411.8", "10", "0", "4"], price, size, deprecated, number of orders
+	err := json.Unmarshal(data, &arr)
+	if err != nil {
+		return err
+	}
+
+	if len(arr) < 2 {
+		return ErrShortBookLevelArray
+	}
+
+	l.Px, l.Sz = arr[0], arr[1]
+
+	l.Price, err = strconv.ParseFloat(l.Px, 64)
+	if err != nil {
+		return err
+	}
+
+	l.Size, err = strconv.ParseFloat(l.Sz, 64)
+
+	return err
+}
+
+// WSDataBook order book snapshot or update, depending on WSData action
+type WSDataBook struct {
+	Asks      []BookLevel `json:"asks"`
+	Bids      []BookLevel `json:"bids"`
+	TS        TSms        `json:"ts"`
+	Checksum  int32       `json:"checksum"`
+	SeqID     int64       `json:"seqId"`
+	PrevSeqID int64       `json:"prevSeqId"`
+}
+
+// BookChecksum calculates okx order book checksum from bids sorted by price descending
+// and asks sorted by price ascending
+func BookChecksum(bids, asks []BookLevel) int32 {
+	parts := make([]string, 0, 4*ChecksumDepth)
+
+	for i := 0; i < ChecksumDepth; i++ {
+		if i < len(bids) {
+			parts = append(parts, bids[i].Px, bids[i].Sz)
+		}
+
+		if i < len(asks) {
+			parts = append(parts, asks[i].Px, asks[i].Sz)
+		}
+	}
+
+	return int32(crc32.ChecksumIEEE([]byte(strings.Join(parts, ":")))) //nolint:gosec // okx checksum is signed crc32
+}
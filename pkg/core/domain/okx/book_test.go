@@ -0,0 +1,52 @@
+package okx
+
+import (
+	"encoding/json"
+	"hash/crc32"
+	"testing"
+)
+
+func TestBookChecksum(t *testing.T) {
+	// Example from okx docs, checksum string is "3366.1:7:3366.8:9:3366:6:3368:8"
+	data := `{
+		"bids": [["3366.1", "7", "0", "3"], ["3366", "6", "3", "4"]],
+		"asks": [["3366.8", "9", "10", "3"], ["3368", "8", "3", "4"]]
+	}`
+
+	book := WSDataBook{}
+	if err := json.Unmarshal([]byte(data), &book); err != nil {
+		t.Fatal(err)
+	}
+
+	if checksum := BookChecksum(book.Bids, book.Asks); checksum != -1881014294 {
+		t.Errorf("got checksum %d, want -1881014294", checksum)
+	}
+}
+
+func TestBookChecksumUnevenSides(t *testing.T) {
+	bids := []BookLevel{{Px: "3366.1", Sz: "7"}, {Px: "3366", Sz: "6"}}
+	asks := []BookLevel{{Px: "3366.8", Sz: "9"}}
+
+	// Remaining levels of longer side are appended after shorter side ends
+	want := int32(crc32.ChecksumIEEE([]byte("3366.1:7:3366.8:9:3366:6"))) //nolint:gosec // okx checksum is signed crc32
+
+	if checksum := BookChecksum(bids, asks); checksum != want {
+		t.Errorf("got checksum %d, want %d", checksum, want)
+	}
+}
+
+func TestBookLevelUnmarshal(t *testing.T) {
+	level := BookLevel{}
+
+	if err := json.Unmarshal([]byte(`["411.8", "10", "0", "4"]`), &level); err != nil {
+		t.Fatal(err)
+	}
+
+	if level.Px != "411.8" || level.Sz != "10" || level.Price != 411.8 || level.Size != 10 {
+		t.Errorf("unexpected level %+v", level)
+	}
+
+	if err := json.Unmarshal([]byte(`["411.8"]`), &level); err == nil {
+		t.Error("expected error for short level array")
+	}
+}
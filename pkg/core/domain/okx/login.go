@@ -0,0 +1,61 @@
+package okx
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Method and path signed on websocket login
+const loginSignPayload = "GET/users/self/verify"
+
+type WSLoginArgument struct {
+	APIKey     string `json:"apiKey"`
+	Passphrase string `json:"passphrase"`
+	Timestamp  string `json:"timestamp"`
+	Sign       string `json:"sign"`
+}
+
+// WSLoginRequest login request to okx private wss API
+type WSLoginRequest struct {
+	Op   Operation         `json:"op"`
+	Args []WSLoginArgument `json:"args"`
+}
+
+// LoginSign returns Base64(HMAC_SHA256(timestamp + "GET" + "/users/self/verify", secret))
+func LoginSign(secret, timestamp string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + loginSignPayload))
+
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// NewWSLoginRequest returns signed login request for given time
+func NewWSLoginRequest(apiKey, secret, passphrase string, now time.Time) *WSLoginRequest {
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+
+	return &WSLoginRequest{
+		Op: OperationLogin,
+		Args: []WSLoginArgument{
+			{
+				APIKey:     apiKey,
+				Passphrase: passphrase,
+				Timestamp:  timestamp,
+				Sign:       LoginSign(secret, timestamp),
+			},
+		},
+	}
+}
+
+// LoginError okx rejected login request
+type LoginError struct {
+	Code string
+	Msg  string
+}
+
+func (e *LoginError) Error() string {
+	return fmt.Sprintf("okx login failed with code %s: %s", e.Code, e.Msg)
+}
@@ -0,0 +1,84 @@
+package okx
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Options expire at 08:00 UTC of expiry date
+const optionExpiryHour = 8 * time.Hour
+
+type OptionType string
+
+const (
+	OptionTypeCall OptionType = "C"
+	OptionTypePut  OptionType = "P"
+)
+
+// Option parsed option instrument id, i.e. BTC-USD-250328-90000-C
+type Option struct {
+	Underlying Instrument
+	Expiry     string
+	Strike     string
+	Type       OptionType
+}
+
+// StrikeFloat returns strike price, 0 if it can't be parsed
+func (o Option) StrikeFloat() float64 {
+	f, err := strconv.ParseFloat(o.Strike, 64)
+	if err != nil {
+		return 0
+	}
+
+	return f
+}
+
+// ExpiryTime returns time option expires at, false if expiry date can't be parsed
+func (o Option) ExpiryTime() (time.Time, bool) {
+	date, err := time.Parse("060102", o.Expiry)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return date.Add(optionExpiryHour), true
+}
+
+// Option parses option instrument id, ok is false if instrument is not an option
+func (i Instrument) Option() (opt Option, ok bool) {
+	const optionParts = 5
+
+	parts := strings.Split(string(i), "-")
+	if len(parts) != optionParts {
+		return Option{}, false
+	}
+
+	optType := OptionType(parts[4])
+	if optType != OptionTypeCall && optType != OptionTypePut {
+		return Option{}, false
+	}
+
+	return Option{
+		Underlying: Instrument(parts[0] + "-" + parts[1]),
+		Expiry:     parts[2],
+		Strike:     parts[3],
+		Type:       optType,
+	}, true
+}
+
+// WSDataOptSummary option greeks and implied volatilities from opt-summary channel
+type WSDataOptSummary struct {
+	InstType string     `json:"instType"`
+	InstID   Instrument `json:"instId"`
+	Uly      Instrument `json:"uly"`
+	Delta    Number     `json:"delta"`
+	Gamma    Number     `json:"gamma"`
+	Vega     Number     `json:"vega"`
+	Theta    Number     `json:"theta"`
+	MarkVol  Number     `json:"markVol"`
+	BidVol   Number     `json:"bidVol"`
+	AskVol   Number     `json:"askVol"`
+	RealVol  Number     `json:"realVol"`
+	FwdPx    Number     `json:"fwdPx"`
+	TS       TSms       `json:"ts"`
+}
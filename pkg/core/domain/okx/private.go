@@ -0,0 +1,119 @@
+package okx
+
+// Private channels, available after login
+const (
+	ChannelAccount   Channel = "account"
+	ChannelPositions Channel = "positions"
+	ChannelOrders    Channel = "orders"
+)
+
+// Channels available only on connection logged in with API key
+var loginChannels = map[Channel]bool{
+	ChannelAccount:   true,
+	ChannelPositions: true,
+	ChannelOrders:    true,
+}
+
+// RequiresLogin reports whether channel can be subscribed only after login
+func (c Channel) RequiresLogin() bool {
+	return loginChannels[c]
+}
+
+// InstTypeAny subscribes to private channel for all instrument types
+const InstTypeAny = "ANY"
+
+// WSDataAccount account balance and margin summary
+type WSDataAccount struct {
+	UTime       TSms                  `json:"uTime"`
+	TotalEq     Number                `json:"totalEq"`
+	IsoEq       Number                `json:"isoEq"`
+	AdjEq       Number                `json:"adjEq"`
+	Imr         Number                `json:"imr"`
+	Mmr         Number                `json:"mmr"`
+	MgnRatio    Number                `json:"mgnRatio"`
+	NotionalUsd Number                `json:"notionalUsd"`
+	Upl         Number                `json:"upl"`
+	Details     []WSDataAccountDetail `json:"details"`
+}
+
+// WSDataAccountDetail balance of one currency
+type WSDataAccountDetail struct {
+	Ccy       string `json:"ccy"`
+	Eq        Number `json:"eq"`
+	CashBal   Number `json:"cashBal"`
+	AvailBal  Number `json:"availBal"`
+	AvailEq   Number `json:"availEq"`
+	FrozenBal Number `json:"frozenBal"`
+	OrdFrozen Number `json:"ordFrozen"`
+	EqUsd     Number `json:"eqUsd"`
+	Upl       Number `json:"upl"`
+	UTime     TSms   `json:"uTime"`
+}
+
+// WSDataPosition position of instrument, Pos is 0 when position is closed
+type WSDataPosition struct {
+	InstType string     `json:"instType"`
+	InstID   Instrument `json:"instId"`
+	MgnMode  string     `json:"mgnMode"`
+	PosID    string     `json:"posId"`
+	PosSide  string     `json:"posSide"`
+	Pos      Number     `json:"pos"`
+	AvgPx    Number     `json:"avgPx"`
+	Upl      Number     `json:"upl"`
+	Lever    Number     `json:"lever"`
+	LiqPx    Number     `json:"liqPx"`
+	MarkPx   Number     `json:"markPx"`
+	MgnRatio Number     `json:"mgnRatio"`
+	UTime    TSms       `json:"uTime"`
+}
+
+// LiquidationDistancePercent distance between mark and liquidation prices relative to mark price in percents,
+// ok is false if there is no liquidation price
+func (p WSDataPosition) LiquidationDistancePercent() (distance float64, ok bool) {
+	if p.LiqPx == 0 || p.MarkPx == 0 {
+		return 0, false
+	}
+
+	distance = float64((p.MarkPx - p.LiqPx) / p.MarkPx * 100)
+	if distance < 0 {
+		distance = -distance
+	}
+
+	return distance, true
+}
+
+type OrderState string
+
+const (
+	OrderStateLive            OrderState = "live"
+	OrderStatePartiallyFilled OrderState = "partially_filled"
+	OrderStateFilled          OrderState = "filled"
+	OrderStateCanceled        OrderState = "canceled"
+	OrderStateMMPCanceled     OrderState = "mmp_canceled"
+)
+
+// Final reports whether order won't be updated anymore
+func (s OrderState) Final() bool {
+	return s == OrderStateFilled || s == OrderStateCanceled || s == OrderStateMMPCanceled
+}
+
+// WSDataOrder order update, Fee is accumulated fee, negative when charged and positive for rebates
+type WSDataOrder struct {
+	InstType  string     `json:"instType"`
+	InstID    Instrument `json:"instId"`
+	OrdID     string     `json:"ordId"`
+	OrdType   string     `json:"ordType"`
+	Side      `json:"side"`
+	Px        Number     `json:"px"`
+	Sz        Number     `json:"sz"`
+	State     OrderState `json:"state"`
+	AccFillSz Number     `json:"accFillSz"`
+	FillSz    Number     `json:"fillSz"`
+	FillPx    Number     `json:"fillPx"`
+	FillTime  TSms       `json:"fillTime"`
+	FillFee   Number     `json:"fillFee"`
+	Fee       Number     `json:"fee"`
+	FeeCcy    string     `json:"feeCcy"`
+	CTime     TSms       `json:"cTime"`
+	UTime     TSms       `json:"uTime"`
+}
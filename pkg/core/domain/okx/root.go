@@ -3,7 +3,9 @@ package okx
 import (
 	"encoding/json"
 	"errors"
+	"regexp"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -17,44 +19,158 @@ const (
 	OperationEmpty       Operation = ""
 )
 
+// Text keepalive messages, okx closes connection when there is no data for 30 seconds
+const (
+	MessagePing = "ping"
+	MessagePong = "pong"
+)
+
 type Channel string
 
 const (
 	ChannelTickers          Channel = "tickers"
 	ChannelInstruments      Channel = "instruments"
-	ChannelCandle1H         Channel = "candle1H"
 	ChannelAggregatedTrades Channel = "aggregated-trades"
+	ChannelTrades           Channel = "trades"
+	ChannelTradesAll        Channel = "trades-all"
+	ChannelFundingRate      Channel = "funding-rate"
+	ChannelOpenInterest     Channel = "open-interest"
+	ChannelMarkPrice        Channel = "mark-price"
+	ChannelIndexTickers     Channel = "index-tickers"
+	ChannelPriceLimit       Channel = "price-limit"
+	ChannelLiquidations     Channel = "liquidation-orders"
+	ChannelOptSummary       Channel = "opt-summary"
+	ChannelEstimatedPrice   Channel = "estimated-price"
+	ChannelStatus           Channel = "status"
+	ChannelBooks            Channel = "books"
+	ChannelBooks5           Channel = "books5"
+	ChannelBBOTbt           Channel = "bbo-tbt"
 )
 
-// Used in okx_volume metric candle parameter
+// Candle channels, interval is a channel name without ChannelCandlePrefix
 const (
-	Candle1H string = "1H"
+	ChannelCandlePrefix = "candle"
+
+	ChannelCandle3M     Channel = "candle3M"
+	ChannelCandle1M     Channel = "candle1M"
+	ChannelCandle1W     Channel = "candle1W"
+	ChannelCandle1D     Channel = "candle1D"
+	ChannelCandle2D     Channel = "candle2D"
+	ChannelCandle3D     Channel = "candle3D"
+	ChannelCandle5D     Channel = "candle5D"
+	ChannelCandle12H    Channel = "candle12H"
+	ChannelCandle6H     Channel = "candle6H"
+	ChannelCandle4H     Channel = "candle4H"
+	ChannelCandle2H     Channel = "candle2H"
+	ChannelCandle1H     Channel = "candle1H"
+	ChannelCandle30m    Channel = "candle30m"
+	ChannelCandle15m    Channel = "candle15m"
+	ChannelCandle5m     Channel = "candle5m"
+	ChannelCandle3m     Channel = "candle3m"
+	ChannelCandle1m     Channel = "candle1m"
+	ChannelCandle1s     Channel = "candle1s"
+	ChannelCandle3Mutc  Channel = "candle3Mutc"
+	ChannelCandle1Mutc  Channel = "candle1Mutc"
+	ChannelCandle1Wutc  Channel = "candle1Wutc"
+	ChannelCandle1Dutc  Channel = "candle1Dutc"
+	ChannelCandle2Dutc  Channel = "candle2Dutc"
+	ChannelCandle3Dutc  Channel = "candle3Dutc"
+	ChannelCandle5Dutc  Channel = "candle5Dutc"
+	ChannelCandle12Hutc Channel = "candle12Hutc"
+	ChannelCandle6Hutc  Channel = "candle6Hutc"
 )
 
+var candleChannels = map[Channel]bool{
+	ChannelCandle3M:     true,
+	ChannelCandle1M:     true,
+	ChannelCandle1W:     true,
+	ChannelCandle1D:     true,
+	ChannelCandle2D:     true,
+	ChannelCandle3D:     true,
+	ChannelCandle5D:     true,
+	ChannelCandle12H:    true,
+	ChannelCandle6H:     true,
+	ChannelCandle4H:     true,
+	ChannelCandle2H:     true,
+	ChannelCandle1H:     true,
+	ChannelCandle30m:    true,
+	ChannelCandle15m:    true,
+	ChannelCandle5m:     true,
+	ChannelCandle3m:     true,
+	ChannelCandle1m:     true,
+	ChannelCandle1s:     true,
+	ChannelCandle3Mutc:  true,
+	ChannelCandle1Mutc:  true,
+	ChannelCandle1Wutc:  true,
+	ChannelCandle1Dutc:  true,
+	ChannelCandle2Dutc:  true,
+	ChannelCandle3Dutc:  true,
+	ChannelCandle5Dutc:  true,
+	ChannelCandle12Hutc: true,
+	ChannelCandle6Hutc:  true,
+}
+
+// IsCandle reports whether channel is one of candle channels
+func (c Channel) IsCandle() bool {
+	return candleChannels[c]
+}
+
+// CandleInterval returns candle interval, used in candle metrics label, i.e. 1H for candle1H
+func (c Channel) CandleInterval() string {
+	return strings.TrimPrefix(string(c), ChannelCandlePrefix)
+}
+
 type Instrument string
 
 const (
 	InstrumentETHxUSDT Instrument = "ETH-USDT"
 )
 
+// instIDPattern matches okx instrument ids, i.e. BTC-USDT, BTC-USDT-SWAP, BTC-USD-250328 or BTC-USD-250328-90000-C
+var instIDPattern = regexp.MustCompile(`^[A-Z0-9]+(-[A-Z0-9.]+){1,4}$`)
+
+// Valid reports whether instrument id has okx instId format
+func (i Instrument) Valid() bool {
+	return instIDPattern.MatchString(string(i))
+}
+
+// Underlying returns index id of instrument, i.e. BTC-USDT for BTC-USDT-SWAP
+func (i Instrument) Underlying() Instrument {
+	parts := strings.SplitN(string(i), "-", 3)
+	if len(parts) < 2 {
+		return i
+	}
+
+	return Instrument(parts[0] + "-" + parts[1])
+}
+
+// IsSpot reports whether instrument is SPOT or MARGIN one, derivative ids have more than two parts
+func (i Instrument) IsSpot() bool {
+	return strings.Count(string(i), "-") == 1
+}
+
 type Action string
 
 const (
-	ActionUpdate Action = "update"
+	ActionSnapshot Action = "snapshot"
+	ActionUpdate   Action = "update"
 )
 
 type WSArgument struct {
-	Channel `json:"channel"`
-	InstID  Instrument `json:"instId,omitempty"`
+	Channel    `json:"channel"`
+	InstType   string     `json:"instType,omitempty"`
+	InstFamily string     `json:"instFamily,omitempty"`
+	InstID     Instrument `json:"instId,omitempty"`
 }
 
 type WSSubscriptionTopic struct {
 	WSArgument
-	InstID Instrument `json:"instId"`
+	InstID Instrument `json:"instId,omitempty"`
 }
 
-// WSRequest request to okx wss API
+// WSRequest request to okx wss API, ID is returned in response events
 type WSRequest struct {
+	ID   string                `json:"id,omitempty"`
 	Op   Operation             `json:"op"`
 	Args []WSSubscriptionTopic `json:"args"`
 }
@@ -76,35 +192,110 @@ func TSmsFromString(str string) (TSms, error) {
 	return TSms{update}, nil
 }
 
+// UnmarshalJSON decodes timestamp in milliseconds encoded as string, other JSON types are rejected
 func (t *TSms) UnmarshalJSON(data []byte) error {
-	var err error
+	var str string
 
-	// Ignore null, like in the main JSON package.
-	if string(data) == "null" || string(data) == `""` {
-		return nil
+	// Null is decoded as empty string and ignored, like in the main JSON package.
+	err := json.Unmarshal(data, &str)
+	if err != nil || str == "" {
+		return err
 	}
 
-	*t, err = TSmsFromString(string(data[1 : len(data)-1]))
+	*t, err = TSmsFromString(str)
 
 	return err
 }
 
+// Number JSON unmarshallable float encoded as string, empty string is decoded as 0
+type Number float64
+
+func (n *Number) UnmarshalJSON(data []byte) error {
+	var str string
+
+	if err := json.Unmarshal(data, &str); err != nil {
+		return err
+	}
+
+	if str == "" {
+		*n = 0
+		return nil
+	}
+
+	f, err := strconv.ParseFloat(str, 64)
+	if err != nil {
+		return err
+	}
+
+	*n = Number(f)
+
+	return nil
+}
+
+// OptionalNumber Number which okx may send as empty string, i.e. deprecated field.
+// Set reports whether it was not empty.
+type OptionalNumber struct {
+	Value Number
+	Set   bool
+}
+
+func (n *OptionalNumber) UnmarshalJSON(data []byte) error {
+	var str string
+
+	if err := json.Unmarshal(data, &str); err != nil {
+		return err
+	}
+
+	n.Set = str != ""
+
+	return n.Value.UnmarshalJSON(data)
+}
+
 type WSDataTickers struct {
-	InstType string     `json:"instType"`
-	InstID   Instrument `json:"instId"`
-	Last     string     `json:"last"`
-	High24h  string     `json:"high24h"`
-	Low24h   string     `json:"low24h"`
-	TS       TSms       `json:"ts"`
+	InstType  string     `json:"instType"`
+	InstID    Instrument `json:"instId"`
+	Last      Number     `json:"last"`
+	LastSz    Number     `json:"lastSz"`
+	AskPx     Number     `json:"askPx"`
+	AskSz     Number     `json:"askSz"`
+	BidPx     Number     `json:"bidPx"`
+	BidSz     Number     `json:"bidSz"`
+	Open24h   Number     `json:"open24h"`
+	High24h   Number     `json:"high24h"`
+	Low24h    Number     `json:"low24h"`
+	VolCcy24h Number     `json:"volCcy24h"`
+	Vol24h    Number     `json:"vol24h"`
+	SodUtc0   Number     `json:"sodUtc0"`
+	SodUtc8   Number     `json:"sodUtc8"`
+	TS        TSms       `json:"ts"`
 }
 
 func (t WSDataTickers) LastFloat() float64 {
-	f, err := strconv.ParseFloat(t.Last, 64)
-	if err != nil {
-		panic(err)
+	return float64(t.Last)
+}
+
+// HasBid reports whether bid side is not empty, okx sends empty bidPx when it is
+func (t WSDataTickers) HasBid() bool {
+	return t.BidPx != 0
+}
+
+// HasAsk reports whether ask side is not empty, okx sends empty askPx when it is
+func (t WSDataTickers) HasAsk() bool {
+	return t.AskPx != 0
+}
+
+// Spread difference between best ask and best bid prices, meaningful only when both sides are not empty
+func (t WSDataTickers) Spread() float64 {
+	return float64(t.AskPx - t.BidPx)
+}
+
+// Change24hPercent change of last price relative to open price 24h ago in percents
+func (t WSDataTickers) Change24hPercent() float64 {
+	if t.Open24h == 0 {
+		return 0
 	}
 
-	return f
+	return float64((t.Last - t.Open24h) / t.Open24h * 100)
 }
 
 // Aggregated trades related structures
@@ -115,23 +306,30 @@ const (
 	SideBuy  Side = "buy"
 )
 
+// WSDataTrade trade from trades, trades-all or aggregated-trades channels
 type WSDataTrade struct {
-	FId    string     `json:"fId"`
-	LId    string     `json:"lId"`
-	InstID Instrument `json:"instId,omitempty"`
-	PX     string     `json:"px"`
-	Side   `json:"side"`
-	SZ     string `json:"sz"`
-	TS     TSms   `json:"ts"`
+	FId     string     `json:"fId"`
+	LId     string     `json:"lId"`
+	TradeID string     `json:"tradeId"`
+	InstID  Instrument `json:"instId,omitempty"`
+	PX      Number     `json:"px"`
+	Side    `json:"side"`
+	SZ      Number `json:"sz"`
+	Count   Number `json:"count"`
+	TS      TSms   `json:"ts"`
 }
 
 func (t WSDataTrade) SZFloat() float64 {
-	f, err := strconv.ParseFloat(t.SZ, 64)
-	if err != nil {
-		panic(err)
+	return float64(t.SZ)
+}
+
+// TradesCount amount of trades aggregated in this one, at least 1
+func (t WSDataTrade) TradesCount() float64 {
+	if t.Count < 1 {
+		return 1
 	}
 
-	return f
+	return float64(t.Count)
 }
 
 type WSDataCandle struct {
@@ -202,10 +400,205 @@ func (c *WSDataCandle) UnmarshalJSON(data []byte) error {
 	return err
 }
 
-// WSData a message from okx wss API
+// WSDataFundingRate perpetual swap funding rate
+type WSDataFundingRate struct {
+	InstType        string         `json:"instType"`
+	InstID          Instrument     `json:"instId"`
+	FundingRate     Number         `json:"fundingRate"`
+	NextFundingRate OptionalNumber `json:"nextFundingRate"`
+	FundingTime     TSms           `json:"fundingTime"`
+	NextFundingTime TSms           `json:"nextFundingTime"`
+	TS              TSms           `json:"ts"`
+}
+
+// WSDataOpenInterest open interest of SWAP, FUTURES or OPTION instrument
+type WSDataOpenInterest struct {
+	InstType string     `json:"instType"`
+	InstID   Instrument `json:"instId"`
+	OI       Number     `json:"oi"`
+	OICcy    Number     `json:"oiCcy"`
+	TS       TSms       `json:"ts"`
+}
+
+// WSDataMarkPrice mark price of SWAP, FUTURES, OPTION or MARGIN instrument
+type WSDataMarkPrice struct {
+	InstType string     `json:"instType"`
+	InstID   Instrument `json:"instId"`
+	MarkPx   Number     `json:"markPx"`
+	TS       TSms       `json:"ts"`
+}
+
+// WSDataIndexTickers index ticker, InstID is index id, i.e. BTC-USDT
+type WSDataIndexTickers struct {
+	InstID  Instrument `json:"instId"`
+	IdxPx   Number     `json:"idxPx"`
+	Open24h Number     `json:"open24h"`
+	High24h Number     `json:"high24h"`
+	Low24h  Number     `json:"low24h"`
+	SodUtc0 Number     `json:"sodUtc0"`
+	SodUtc8 Number     `json:"sodUtc8"`
+	TS      TSms       `json:"ts"`
+}
+
+// WSDataPriceLimit highest buy and lowest sell limit prices of instrument
+type WSDataPriceLimit struct {
+	InstID  Instrument `json:"instId"`
+	BuyLmt  Number     `json:"buyLmt"`
+	SellLmt Number     `json:"sellLmt"`
+	Enabled bool       `json:"enabled"`
+	TS      TSms       `json:"ts"`
+}
+
+// WSDataLiquidation liquidation orders of one instrument
+type WSDataLiquidation struct {
+	InstType   string                    `json:"instType"`
+	InstFamily string                    `json:"instFamily"`
+	InstID     Instrument                `json:"instId"`
+	Details    []WSDataLiquidationDetail `json:"details"`
+}
+
+type WSDataLiquidationDetail struct {
+	Side    `json:"side"`
+	PosSide string `json:"posSide"`
+	BkPx    Number `json:"bkPx"`
+	Sz      Number `json:"sz"`
+	BkLoss  Number `json:"bkLoss"`
+	Ccy     string `json:"ccy"`
+	TS      TSms   `json:"ts"`
+}
+
+// WSDataEstimatedPrice estimated delivery, exercise or settlement price of FUTURES or OPTION instrument
+type WSDataEstimatedPrice struct {
+	InstType   string     `json:"instType"`
+	InstID     Instrument `json:"instId"`
+	SettleType string     `json:"settleType"`
+	SettlePx   Number     `json:"settlePx"`
+	TS         TSms       `json:"ts"`
+}
+
+type MaintenanceState string
+
+const (
+	MaintenanceStateScheduled MaintenanceState = "scheduled"
+	MaintenanceStateOngoing   MaintenanceState = "ongoing"
+	MaintenanceStatePreOpen   MaintenanceState = "pre_open"
+	MaintenanceStateCompleted MaintenanceState = "completed"
+	MaintenanceStateCanceled  MaintenanceState = "canceled"
+)
+
+// WSDataStatus system maintenance announcement
+type WSDataStatus struct {
+	Title       string           `json:"title"`
+	State       MaintenanceState `json:"state"`
+	Begin       TSms             `json:"begin"`
+	End         TSms             `json:"end"`
+	ServiceType string           `json:"serviceType"`
+	System      string           `json:"system"`
+	MaintType   string           `json:"maintType"`
+	TS          TSms             `json:"ts"`
+}
+
+type InstrumentState string
+
+const (
+	InstrumentStateLive    InstrumentState = "live"
+	InstrumentStateSuspend InstrumentState = "suspend"
+	InstrumentStatePreOpen InstrumentState = "preopen"
+	InstrumentStateTest    InstrumentState = "test"
+)
+
+// WSDataInstrument instrument metadata from instruments channel, numeric values are kept as sent by okx
+type WSDataInstrument struct {
+	InstType   string          `json:"instType"`
+	InstID     Instrument      `json:"instId"`
+	InstFamily string          `json:"instFamily"`
+	Uly        string          `json:"uly"`
+	BaseCcy    string          `json:"baseCcy"`
+	QuoteCcy   string          `json:"quoteCcy"`
+	SettleCcy  string          `json:"settleCcy"`
+	CtVal      string          `json:"ctVal"`
+	CtMult     string          `json:"ctMult"`
+	CtValCcy   string          `json:"ctValCcy"`
+	OptType    string          `json:"optType"`
+	Stk        string          `json:"stk"`
+	ListTime   string          `json:"listTime"`
+	ExpTime    string          `json:"expTime"`
+	Lever      string          `json:"lever"`
+	TickSz     string          `json:"tickSz"`
+	LotSz      string          `json:"lotSz"`
+	MinSz      string          `json:"minSz"`
+	CtType     string          `json:"ctType"`
+	State      InstrumentState `json:"state"`
+}
+
+// QuoteCurrency returns quote currency, taken from instrument id for derivatives which have empty quoteCcy
+func (i WSDataInstrument) QuoteCurrency() string {
+	if i.QuoteCcy != "" {
+		return i.QuoteCcy
+	}
+
+	parts := strings.SplitN(string(i.InstID), "-", 3)
+	if len(parts) < 2 {
+		return ""
+	}
+
+	return parts[1]
+}
+
+// Expiry returns expiry time, zero if instrument has no expiry or it can't be parsed
+func (i WSDataInstrument) Expiry() time.Time {
+	ts, err := TSmsFromString(i.ExpTime)
+	if err != nil {
+		return time.Time{}
+	}
+
+	return ts.Time
+}
+
+// ContractTypeInverse contract type of derivatives margined in base currency, i.e. BTC-USD-SWAP
+const ContractTypeInverse = "inverse"
+
+// Contract converts size of derivative trades in contracts to value
+type Contract struct {
+	// Value amount of value currency in one contract
+	Value   float64
+	Inverse bool
+}
+
+// SpotContract is used for SPOT instruments, which size is in base currency
+var SpotContract = Contract{Value: 1}
+
+// Contract returns contract of derivative instrument, false if it has no contract value
+func (i WSDataInstrument) Contract() (Contract, bool) {
+	value, err := strconv.ParseFloat(i.CtVal, 64)
+	if err != nil || value == 0 {
+		return Contract{}, false
+	}
+
+	if mult, err := strconv.ParseFloat(i.CtMult, 64); err == nil && mult != 0 {
+		value *= mult
+	}
+
+	return Contract{Value: value, Inverse: i.CtType == ContractTypeInverse}, true
+}
+
+// Notional value of size in contracts at price. It is in quote currency for linear contracts
+// and in value currency (USD) for inverse ones, which value is fixed in it.
+func (c Contract) Notional(px, sz float64) float64 {
+	if c.Inverse {
+		return sz * c.Value
+	}
+
+	return px * sz * c.Value
+}
+
+// WSData a message from okx wss API, Code and Msg are set in event messages
 type WSData struct {
 	Action `json:"action,omitempty"`
+	ID     string            `json:"id,omitempty"`
 	Event  Operation         `json:"event,omitempty"`
+	Code   string            `json:"code,omitempty"`
+	Msg    string            `json:"msg,omitempty"`
 	Arg    WSArgument        `json:"arg"`
 	Data   []json.RawMessage `json:"data"`
 }
@@ -0,0 +1,52 @@
+package okx
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestTSmsUnmarshal(t *testing.T) {
+	ts := TSms{}
+
+	if err := json.Unmarshal([]byte(`"1597026383085"`), &ts); err != nil {
+		t.Fatal(err)
+	}
+
+	if ts.UnixMilli() != 1597026383085 {
+		t.Errorf("got %d, want 1597026383085", ts.UnixMilli())
+	}
+
+	for _, data := range []string{`null`, `""`} {
+		ts = TSms{}
+
+		if err := json.Unmarshal([]byte(data), &ts); err != nil || !ts.IsZero() {
+			t.Errorf("%s: got %v, %v, want zero time", data, ts, err)
+		}
+	}
+
+	for _, data := range []string{`1`, `1597026383085`, `"abc"`, `{}`} {
+		if err := json.Unmarshal([]byte(data), &ts); err == nil {
+			t.Errorf("%s: expected error", data)
+		}
+	}
+}
+
+func TestOptionalNumberUnmarshal(t *testing.T) {
+	rate := WSDataFundingRate{}
+
+	if err := json.Unmarshal([]byte(`{"fundingRate":"0.0001","nextFundingRate":""}`), &rate); err != nil {
+		t.Fatal(err)
+	}
+
+	if rate.NextFundingRate.Set {
+		t.Errorf("empty next funding rate is set: %+v", rate.NextFundingRate)
+	}
+
+	if err := json.Unmarshal([]byte(`{"nextFundingRate":"0.0002"}`), &rate); err != nil {
+		t.Fatal(err)
+	}
+
+	if !rate.NextFundingRate.Set || rate.NextFundingRate.Value != 0.0002 {
+		t.Errorf("got %+v, want 0.0002", rate.NextFundingRate)
+	}
+}
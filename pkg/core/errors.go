@@ -0,0 +1,45 @@
+package core
+
+import (
+	"encoding/json"
+
+	"github.com/gavt45/okx-exporter/pkg/core/domain/okx"
+	"github.com/pkg/errors"
+)
+
+var ErrEmptyData error = errors.New("message has no data")
+
+// MessageError message can't be processed as it has unexpected format, such messages should be skipped
+type MessageError struct {
+	Channel okx.Channel
+	Err     error
+}
+
+func (e *MessageError) Error() string {
+	return "malformed message of " + string(e.Channel) + " channel: " + e.Err.Error()
+}
+
+func (e *MessageError) Unwrap() error {
+	return e.Err
+}
+
+// parseError wraps data parse error into MessageError
+func parseError(data okx.WSData, err error, what string) error {
+	return &MessageError{
+		Channel: data.Arg.Channel,
+		Err:     errors.Wrap(err, "can't parse data as data for "+what),
+	}
+}
+
+// unmarshalFirst decodes first data item of channels sending one item in message, empty data is malformed for them
+func unmarshalFirst(data okx.WSData, v interface{}, what string) error {
+	if len(data.Data) == 0 {
+		return &MessageError{Channel: data.Arg.Channel, Err: ErrEmptyData}
+	}
+
+	if err := json.Unmarshal(data.Data[0], v); err != nil {
+		return parseError(data, err, what)
+	}
+
+	return nil
+}
@@ -0,0 +1,149 @@
+package core
+
+import (
+	"encoding/json"
+	"slices"
+	"time"
+
+	"github.com/gavt45/okx-exporter/pkg/core/domain/okx"
+	"github.com/gavt45/okx-exporter/pkg/log"
+	"github.com/pkg/errors"
+)
+
+func instrumentInfoLabels(instrument okx.WSDataInstrument) []string {
+	return []string{
+		string(instrument.InstID),
+		instrument.InstType,
+		instrument.InstFamily,
+		instrument.BaseCcy,
+		instrument.QuoteCcy,
+		instrument.SettleCcy,
+		string(instrument.State),
+		instrument.TickSz,
+		instrument.LotSz,
+		instrument.MinSz,
+		instrument.CtVal,
+		instrument.CtValCcy,
+		instrument.CtType,
+		instrument.ListTime,
+		instrument.ExpTime,
+	}
+}
+
+// exportInstrumentInfo sets okx_instrument_info for instrument, removing label set exported for it before
+func (s *Service) exportInstrumentInfo(instrument okx.WSDataInstrument) {
+	labels := instrumentInfoLabels(instrument)
+
+	if prev, ok := s.instrumentInfo[instrument.InstID]; ok && !slices.Equal(prev, labels) {
+		mInstrumentInfo.DeleteLabelValues(prev...)
+	}
+
+	s.instrumentInfo[instrument.InstID] = labels
+
+	mInstrumentInfo.WithLabelValues(labels...).Set(1)
+}
+
+// discoverable reports whether instrument matches discovery filters
+func (s *Service) discoverable(instrument okx.WSDataInstrument) bool {
+	if !slices.Contains(s.cfg.Discovery.InstTypes, instrument.InstType) {
+		return false
+	}
+
+	if len(s.cfg.Discovery.QuoteCcys) > 0 && !slices.Contains(s.cfg.Discovery.QuoteCcys, instrument.QuoteCurrency()) {
+		return false
+	}
+
+	return s.discoveryRegex.MatchString(string(instrument.InstID))
+}
+
+// discover subscribes to live instruments matching discovery filters and unsubscribes from
+// suspended or expired ones, deleting their metrics
+func (s *Service) discover(instrument okx.WSDataInstrument, now time.Time) error {
+	if !s.discoverable(instrument) {
+		return nil
+	}
+
+	expired := instrument.ExpTime != "" && instrument.Expiry().Before(now)
+	active := instrument.State == okx.InstrumentStateLive && !expired
+
+	switch {
+	case active && !s.discovered[instrument.InstID]:
+		log.Info("Discovered instrument ", instrument.InstID)
+
+		if err := s.sub.Subscribe(s.instrumentTopics(instrument.InstID)...); err != nil {
+			return errors.Wrap(err, "can't subscribe to discovered instrument "+string(instrument.InstID))
+		}
+
+		s.discovered[instrument.InstID] = true
+	case !active && s.discovered[instrument.InstID]:
+		log.Infof("Removing instrument %s in state %s", instrument.InstID, instrument.State)
+
+		if err := s.sub.Unsubscribe(s.instrumentTopics(instrument.InstID)...); err != nil {
+			return errors.Wrap(err, "can't unsubscribe from removed instrument "+string(instrument.InstID))
+		}
+
+		delete(s.discovered, instrument.InstID)
+		s.forgetInstrument(instrument.InstID)
+	}
+
+	return nil
+}
+
+// notional returns value of size at price for instrument. Derivative size is in contracts,
+// so false is returned until contract value is received from instruments channel.
+func (s *Service) notional(instrument okx.Instrument, px, sz float64) (float64, bool) {
+	if instrument.IsSpot() {
+		return okx.SpotContract.Notional(px, sz), true
+	}
+
+	contract, ok := s.contracts[instrument]
+	if !ok {
+		log.Debug("Contract value is unknown, skipping notional of ", instrument)
+		return 0, false
+	}
+
+	return contract.Notional(px, sz), true
+}
+
+// forgetInstrument deletes instrument state and metrics
+func (s *Service) forgetInstrument(instrument okx.Instrument) {
+	for key := range s.books {
+		if key.instrument == instrument {
+			delete(s.books, key)
+		}
+	}
+
+	delete(s.lastPrices, instrument)
+	delete(s.markPrices, instrument)
+	delete(s.contracts, instrument)
+
+	deleteInstrumentMetrics(instrument)
+}
+
+func (s *Service) processInstruments(data okx.WSData) error {
+	now := time.Now()
+
+	for _, instrumentData := range data.Data {
+		instrument := okx.WSDataInstrument{}
+
+		if err := json.Unmarshal(instrumentData, &instrument); err != nil {
+			return parseError(data, err, "instruments")
+		}
+
+		s.exportInstrumentInfo(instrument)
+
+		if contract, ok := instrument.Contract(); ok {
+			s.contracts[instrument.InstID] = contract
+		}
+
+		if s.cfg.Discovery.Enabled {
+			if err := s.discover(instrument, now); err != nil {
+				return err
+			}
+		}
+	}
+
+	log.Infof("Got instruments data for %d instruments", len(data.Data))
+
+	return nil
+}
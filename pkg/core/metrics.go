@@ -1,14 +1,144 @@
 package core
 
 import (
+	"github.com/gavt45/okx-exporter/pkg/core/domain/okx"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 )
 
 var (
-	mLastPrice = promauto.NewGaugeVec(
+	mPrice = promauto.NewGaugeVec(
 		prometheus.GaugeOpts{
 			Name: "okx_price",
+			Help: "Instrument price, type is one of last, mark, index, buy_limit, sell_limit",
+		},
+		[]string{"instrument", "type"},
+	)
+
+	mMarkLastDeviation = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "okx_mark_last_deviation_percent",
+			Help: "Deviation of mark price from last price, percents",
+		},
+		[]string{"instrument"},
+	)
+
+	mLastIndexDeviation = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "okx_last_index_deviation_percent",
+			Help: "Deviation of last price from index price of instrument underlying, percents",
+		},
+		[]string{"instrument"},
+	)
+
+	mLastSize = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "okx_last_size",
+			Help: "Size of the last trade got from tickers message",
+		},
+		[]string{"instrument"},
+	)
+
+	mBidPrice = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "okx_bid_price",
+			Help: "Best bid price got from tickers message",
+		},
+		[]string{"instrument"},
+	)
+
+	mBidSize = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "okx_bid_size",
+			Help: "Best bid size got from tickers message",
+		},
+		[]string{"instrument"},
+	)
+
+	mAskPrice = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "okx_ask_price",
+			Help: "Best ask price got from tickers message",
+		},
+		[]string{"instrument"},
+	)
+
+	mAskSize = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "okx_ask_size",
+			Help: "Best ask size got from tickers message",
+		},
+		[]string{"instrument"},
+	)
+
+	mSpread = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "okx_spread",
+			Help: "Difference between best ask and best bid prices got from tickers message",
+		},
+		[]string{"instrument"},
+	)
+
+	mOpen24h = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "okx_open_24h",
+			Help: "Open price in the past 24 hours got from tickers message",
+		},
+		[]string{"instrument"},
+	)
+
+	mHigh24h = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "okx_high_24h",
+			Help: "Highest price in the past 24 hours got from tickers message",
+		},
+		[]string{"instrument"},
+	)
+
+	mLow24h = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "okx_low_24h",
+			Help: "Lowest price in the past 24 hours got from tickers message",
+		},
+		[]string{"instrument"},
+	)
+
+	mChange24h = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "okx_change_24h_percent",
+			Help: "Change of last price relative to open price in the past 24 hours, percents",
+		},
+		[]string{"instrument"},
+	)
+
+	mVolume24h = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "okx_volume_24h",
+			Help: "Trading volume in the past 24 hours got from tickers message, in base currency or contracts",
+		},
+		[]string{"instrument"},
+	)
+
+	mVolumeCcy24h = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "okx_volume_ccy_24h",
+			Help: "Trading volume in the past 24 hours got from tickers message, in quote currency or base currency",
+		},
+		[]string{"instrument"},
+	)
+
+	mSodUtc0 = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "okx_sod_utc0",
+			Help: "Open price in the UTC 0 got from tickers message",
+		},
+		[]string{"instrument"},
+	)
+
+	mSodUtc8 = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "okx_sod_utc8",
+			Help: "Open price in the UTC 8 got from tickers message",
 		},
 		[]string{"instrument"},
 	)
@@ -61,6 +191,356 @@ var (
 		[]string{"instrument", "candle"},
 	)
 
+	mBookBestBid = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "okx_book_best_bid",
+			Help: "Best bid price of local order book",
+		},
+		[]string{"instrument", "channel"},
+	)
+
+	mBookBestAsk = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "okx_book_best_ask",
+			Help: "Best ask price of local order book",
+		},
+		[]string{"instrument", "channel"},
+	)
+
+	mBookSpread = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "okx_book_spread",
+			Help: "Difference between best ask and best bid prices of local order book",
+		},
+		[]string{"instrument", "channel"},
+	)
+
+	mBookDepth = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "okx_book_depth",
+			Help: "Summary size of order book side levels within bps basis points from mid price",
+		},
+		[]string{"instrument", "channel", "side", "bps"},
+	)
+
+	mFundingRate = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "okx_funding_rate",
+			Help: "Current funding rate of perpetual swap",
+		},
+		[]string{"instrument"},
+	)
+
+	mNextFundingRate = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "okx_funding_rate_next",
+			Help: "Predicted funding rate for the next period of perpetual swap",
+		},
+		[]string{"instrument"},
+	)
+
+	mFundingTimeLeft = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "okx_funding_time_left_seconds",
+			Help: "Seconds left until next funding time, as of last funding-rate message",
+		},
+		[]string{"instrument"},
+	)
+
+	mOpenInterest = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "okx_open_interest",
+			Help: "Open interest in contracts",
+		},
+		[]string{"instrument"},
+	)
+
+	mOpenInterestCcy = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "okx_open_interest_ccy",
+			Help: "Open interest in currency",
+		},
+		[]string{"instrument"},
+	)
+
+	mLiquidationsSize = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "okx_liquidations_size_total",
+			Help: "Liquidated size got from liquidation-orders channel, in contracts for derivatives",
+		},
+		[]string{"instrument", "side"},
+	)
+
+	mLiquidationsNotional = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "okx_liquidations_notional_total",
+			Help: "Liquidated notional at bankruptcy price got from liquidation-orders channel, in quote currency, " +
+				"USD for inverse contracts. Exported only with instruments channel of instType subscribed",
+		},
+		[]string{"instrument", "side"},
+	)
+
+	mLiquidationSizeHist = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "okx_liquidation_size",
+			Buckets: []float64{0.001, 0.01, 0.1, 1, 5, 10, 100, 1000, 10000},
+		},
+		[]string{"instrument"},
+	)
+
+	mOptionDelta = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "okx_option_delta",
+			Help: "Option delta",
+		},
+		[]string{"underlying", "expiry", "strike", "type"},
+	)
+
+	mOptionGamma = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "okx_option_gamma",
+			Help: "Option gamma",
+		},
+		[]string{"underlying", "expiry", "strike", "type"},
+	)
+
+	mOptionVega = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "okx_option_vega",
+			Help: "Option vega",
+		},
+		[]string{"underlying", "expiry", "strike", "type"},
+	)
+
+	mOptionTheta = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "okx_option_theta",
+			Help: "Option theta",
+		},
+		[]string{"underlying", "expiry", "strike", "type"},
+	)
+
+	mOptionMarkVol = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "okx_option_mark_vol",
+			Help: "Option mark price implied volatility",
+		},
+		[]string{"underlying", "expiry", "strike", "type"},
+	)
+
+	mOptionBidVol = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "okx_option_bid_vol",
+			Help: "Option bid price implied volatility",
+		},
+		[]string{"underlying", "expiry", "strike", "type"},
+	)
+
+	mOptionAskVol = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "okx_option_ask_vol",
+			Help: "Option ask price implied volatility",
+		},
+		[]string{"underlying", "expiry", "strike", "type"},
+	)
+
+	mOptionFwdPrice = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "okx_option_forward_price",
+			Help: "Forward price of option expiry",
+		},
+		[]string{"underlying", "expiry", "strike", "type"},
+	)
+
+	mOptionATMVol = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "okx_option_atm_vol",
+			Help: "At the money implied volatility, average mark volatility of options with strike closest to forward price",
+		},
+		[]string{"underlying", "expiry"},
+	)
+
+	mEstimatedPrice = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "okx_estimated_price",
+			Help: "Estimated delivery, exercise or settlement price, settle_type is one of settlement, delivery, exercise",
+		},
+		[]string{"instrument", "settle_type"},
+	)
+
+	mMaintenanceStart = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "okx_maintenance_start_timestamp_seconds",
+			Help: "Start time of last announced maintenance of okx service type",
+		},
+		[]string{"service_type"},
+	)
+
+	mMaintenanceEnd = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "okx_maintenance_end_timestamp_seconds",
+			Help: "End time of last announced maintenance of okx service type",
+		},
+		[]string{"service_type"},
+	)
+
+	mUnderMaintenance = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "okx_under_maintenance",
+			Help: "1 if okx service type is under maintenance now, 0 otherwise",
+		},
+		[]string{"service_type"},
+	)
+
+	mInstrumentInfo = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "okx_instrument_info",
+			Help: "Instrument metadata got from instruments channel, always 1",
+		},
+		[]string{
+			"instrument", "inst_type", "inst_family", "base_ccy", "quote_ccy", "settle_ccy", "state",
+			"tick_size", "lot_size", "min_size", "contract_value", "contract_value_ccy", "contract_type",
+			"list_time", "expiry_time",
+		},
+	)
+
+	mAccountTotalEquity = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "okx_account_total_equity",
+			Help: "Total equity of account in USD",
+		},
+		[]string{"account"},
+	)
+
+	mAccountMarginRatio = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "okx_account_margin_ratio",
+			Help: "Margin ratio of account",
+		},
+		[]string{"account"},
+	)
+
+	mAccountEquity = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "okx_account_equity",
+			Help: "Equity of currency",
+		},
+		[]string{"account", "currency"},
+	)
+
+	mAccountAvailableBalance = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "okx_account_available_balance",
+			Help: "Available balance of currency",
+		},
+		[]string{"account", "currency"},
+	)
+
+	mAccountFrozenBalance = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "okx_account_frozen_balance",
+			Help: "Frozen balance of currency",
+		},
+		[]string{"account", "currency"},
+	)
+
+	mAccountEquityUsd = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "okx_account_equity_usd",
+			Help: "Equity of currency in USD",
+		},
+		[]string{"account", "currency"},
+	)
+
+	mPositionSize = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "okx_position_size",
+			Help: "Position size, in contracts for derivatives",
+		},
+		[]string{"account", "instrument", "pos_side"},
+	)
+
+	mPositionAvgPrice = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "okx_position_avg_price",
+			Help: "Average open price of position",
+		},
+		[]string{"account", "instrument", "pos_side"},
+	)
+
+	mPositionUpl = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "okx_position_upl",
+			Help: "Unrealized profit and loss of position",
+		},
+		[]string{"account", "instrument", "pos_side"},
+	)
+
+	mPositionMarginRatio = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "okx_position_margin_ratio",
+			Help: "Margin ratio of position",
+		},
+		[]string{"account", "instrument", "pos_side"},
+	)
+
+	mPositionLeverage = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "okx_position_leverage",
+			Help: "Leverage of position",
+		},
+		[]string{"account", "instrument", "pos_side"},
+	)
+
+	mPositionLiquidationPrice = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "okx_position_liquidation_price",
+			Help: "Estimated liquidation price of position",
+		},
+		[]string{"account", "instrument", "pos_side"},
+	)
+
+	mPositionLiquidationDistance = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "okx_position_liquidation_distance_percent",
+			Help: "Distance between mark price and liquidation price relative to mark price, percents",
+		},
+		[]string{"account", "instrument", "pos_side"},
+	)
+
+	mOrders = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "okx_orders_total",
+			Help: "Amount of orders transitioned to state",
+		},
+		[]string{"account", "instrument", "ord_type", "state"},
+	)
+
+	mOrdersFilledVolume = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "okx_orders_filled_volume_total",
+			Help: "Filled volume of orders, in contracts for derivatives",
+		},
+		[]string{"account", "instrument", "side"},
+	)
+
+	mOrdersFees = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "okx_orders_fees_total",
+			Help: "Fees paid for orders, rebates are not counted",
+		},
+		[]string{"account", "instrument", "currency"},
+	)
+
+	mOrderFirstFill = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "okx_order_first_fill_seconds",
+			Help:    "Time from order creation to its first fill",
+			Buckets: []float64{0.01, 0.05, 0.1, 0.5, 1, 5, 10, 60, 300, 3600},
+		},
+		[]string{"account", "instrument", "ord_type"},
+	)
+
 	mLatency = promauto.NewHistogramVec(
 		prometheus.HistogramOpts{
 			Name: "okx_latency",
@@ -68,6 +548,32 @@ var (
 		[]string{"instrument"},
 	)
 
+	mTradesCount = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "okx_trades_total",
+			Help: "Amount of trades got from trades channel",
+		},
+		[]string{"instrument", "channel", "side"},
+	)
+
+	mTradesVolume = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "okx_trades_volume_total",
+			Help: "Traded volume got from trades channel, in base currency or contracts",
+		},
+		[]string{"instrument", "channel", "side"},
+	)
+
+	mTradesNotional = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "okx_trades_notional_total",
+			Help: "Traded notional got from trades channel, in quote currency, USD for inverse contracts. " +
+				"Derivatives are exported only with instruments channel of their instType subscribed, " +
+				"as contract value is taken from it",
+		},
+		[]string{"instrument", "channel", "side"},
+	)
+
 	mTradeSizeHist = promauto.NewHistogramVec(
 		prometheus.HistogramOpts{
 			Name:    "okx_trade_size",
@@ -76,3 +582,79 @@ var (
 		[]string{"instrument"},
 	)
 )
+
+// Metric vectors with instrument label, their label sets are deleted when instrument is removed
+var instrumentVecs = []*prometheus.MetricVec{
+	mPrice.MetricVec,
+	mMarkLastDeviation.MetricVec,
+	mLastIndexDeviation.MetricVec,
+	mLastSize.MetricVec,
+	mBidPrice.MetricVec,
+	mBidSize.MetricVec,
+	mAskPrice.MetricVec,
+	mAskSize.MetricVec,
+	mSpread.MetricVec,
+	mOpen24h.MetricVec,
+	mHigh24h.MetricVec,
+	mLow24h.MetricVec,
+	mChange24h.MetricVec,
+	mVolume24h.MetricVec,
+	mVolumeCcy24h.MetricVec,
+	mSodUtc0.MetricVec,
+	mSodUtc8.MetricVec,
+	mLastTS.MetricVec,
+	mLastOpen.MetricVec,
+	mLastHigh.MetricVec,
+	mLastLow.MetricVec,
+	mLastClose.MetricVec,
+	mLastVolume.MetricVec,
+	mBookBestBid.MetricVec,
+	mBookBestAsk.MetricVec,
+	mBookSpread.MetricVec,
+	mBookDepth.MetricVec,
+	mFundingRate.MetricVec,
+	mNextFundingRate.MetricVec,
+	mFundingTimeLeft.MetricVec,
+	mOpenInterest.MetricVec,
+	mOpenInterestCcy.MetricVec,
+	mLiquidationsSize.MetricVec,
+	mLiquidationsNotional.MetricVec,
+	mLiquidationSizeHist.MetricVec,
+	mEstimatedPrice.MetricVec,
+	mLatency.MetricVec,
+	mTradesCount.MetricVec,
+	mTradesVolume.MetricVec,
+	mTradesNotional.MetricVec,
+	mTradeSizeHist.MetricVec,
+}
+
+// Option metric vectors, label sets of expiry are deleted when it expires
+var optionVecs = []*prometheus.GaugeVec{
+	mOptionDelta,
+	mOptionGamma,
+	mOptionVega,
+	mOptionTheta,
+	mOptionMarkVol,
+	mOptionBidVol,
+	mOptionAskVol,
+	mOptionFwdPrice,
+	mOptionATMVol,
+}
+
+// Position metric vectors, their label sets are deleted when position is closed
+var positionVecs = []*prometheus.GaugeVec{
+	mPositionSize,
+	mPositionAvgPrice,
+	mPositionUpl,
+	mPositionMarginRatio,
+	mPositionLeverage,
+	mPositionLiquidationPrice,
+	mPositionLiquidationDistance,
+}
+
+// deleteInstrumentMetrics deletes all label sets of instrument
+func deleteInstrumentMetrics(instrument okx.Instrument) {
+	for _, vec := range instrumentVecs {
+		vec.DeletePartialMatch(prometheus.Labels{"instrument": string(instrument)})
+	}
+}
@@ -0,0 +1,143 @@
+package core
+
+import (
+	"encoding/json"
+	"math"
+	"time"
+
+	"github.com/gavt45/okx-exporter/pkg/core/domain/okx"
+	"github.com/gavt45/okx-exporter/pkg/log"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type expiryKey struct {
+	underlying okx.Instrument
+	expiry     string
+}
+
+// strikeVols mark volatilities of call and put options with the same strike
+type strikeVols struct {
+	call, put float64
+}
+
+// expiryVols options state of one underlying and expiry, used to find ATM volatility
+type expiryVols struct {
+	fwdPx   float64
+	strikes map[float64]*strikeVols
+}
+
+func (e *expiryVols) update(opt okx.Option, summary okx.WSDataOptSummary) {
+	e.fwdPx = float64(summary.FwdPx)
+
+	vols, ok := e.strikes[opt.StrikeFloat()]
+	if !ok {
+		vols = &strikeVols{}
+		e.strikes[opt.StrikeFloat()] = vols
+	}
+
+	if opt.Type == okx.OptionTypeCall {
+		vols.call = float64(summary.MarkVol)
+	} else {
+		vols.put = float64(summary.MarkVol)
+	}
+}
+
+// atmVol returns average of call and put mark volatilities for strike closest to forward price
+func (e *expiryVols) atmVol() (float64, bool) {
+	var atm *strikeVols
+
+	distance := math.Inf(1)
+
+	for strike, vols := range e.strikes {
+		if d := math.Abs(strike - e.fwdPx); d < distance {
+			distance, atm = d, vols
+		}
+	}
+
+	switch {
+	case atm == nil:
+		return 0, false
+	case atm.call != 0 && atm.put != 0:
+		return (atm.call + atm.put) / 2, true
+	case atm.call != 0:
+		return atm.call, true
+	default:
+		return atm.put, atm.put != 0
+	}
+}
+
+// pruneExpiredOptions forgets expiries which are in the past and deletes their metrics
+func (s *Service) pruneExpiredOptions(now time.Time) {
+	for key := range s.expiryVols {
+		expiry, ok := okx.Option{Expiry: key.expiry}.ExpiryTime()
+		if !ok || expiry.After(now) {
+			continue
+		}
+
+		log.Infof("Removing expired %s options of %s", key.underlying, key.expiry)
+
+		delete(s.expiryVols, key)
+
+		for _, vec := range optionVecs {
+			vec.DeletePartialMatch(prometheus.Labels{"underlying": string(key.underlying), "expiry": key.expiry})
+		}
+	}
+}
+
+func (s *Service) processOptSummary(data okx.WSData) error {
+	now := time.Now()
+	updated := make(map[expiryKey]bool)
+
+	s.pruneExpiredOptions(now)
+
+	for _, summaryData := range data.Data {
+		summary := okx.WSDataOptSummary{}
+
+		if err := json.Unmarshal(summaryData, &summary); err != nil {
+			return parseError(data, err, "option summary")
+		}
+
+		opt, ok := summary.InstID.Option()
+		if !ok {
+			log.Warn("Got option summary for not an option: ", summary.InstID)
+			continue
+		}
+
+		if expiry, ok := opt.ExpiryTime(); ok && !expiry.After(now) {
+			log.Debug("Skipping summary of expired option ", summary.InstID)
+			continue
+		}
+
+		labels := []string{string(opt.Underlying), opt.Expiry, opt.Strike, string(opt.Type)}
+
+		mOptionDelta.WithLabelValues(labels...).Set(float64(summary.Delta))
+		mOptionGamma.WithLabelValues(labels...).Set(float64(summary.Gamma))
+		mOptionVega.WithLabelValues(labels...).Set(float64(summary.Vega))
+		mOptionTheta.WithLabelValues(labels...).Set(float64(summary.Theta))
+		mOptionMarkVol.WithLabelValues(labels...).Set(float64(summary.MarkVol))
+		mOptionBidVol.WithLabelValues(labels...).Set(float64(summary.BidVol))
+		mOptionAskVol.WithLabelValues(labels...).Set(float64(summary.AskVol))
+		mOptionFwdPrice.WithLabelValues(labels...).Set(float64(summary.FwdPx))
+
+		key := expiryKey{underlying: opt.Underlying, expiry: opt.Expiry}
+
+		vols, ok := s.expiryVols[key]
+		if !ok {
+			vols = &expiryVols{strikes: make(map[float64]*strikeVols)}
+			s.expiryVols[key] = vols
+		}
+
+		vols.update(opt, summary)
+		updated[key] = true
+	}
+
+	log.Infof("Got option summary data for %d options", len(data.Data))
+
+	for key := range updated {
+		if atmVol, ok := s.expiryVols[key].atmVol(); ok {
+			mOptionATMVol.WithLabelValues(string(key.underlying), key.expiry).Set(atmVol)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,63 @@
+package core
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/gavt45/okx-exporter/pkg/core/domain/okx"
+)
+
+func optSummaryMessage(instIDs ...string) okx.WSData {
+	data := okx.WSData{Arg: okx.WSArgument{Channel: okx.ChannelOptSummary, InstFamily: "BTC-USD"}}
+
+	for _, instID := range instIDs {
+		item := `{"instId":"` + instID + `","markVol":"0.5","fwdPx":"90000","ts":"1597026383085"}`
+		data.Data = append(data.Data, json.RawMessage(item))
+	}
+
+	return data
+}
+
+func TestProcessOptSummaryPrunesExpired(t *testing.T) {
+	svc, _ := newTestService(t)
+
+	future := time.Now().AddDate(0, 1, 0).Format("060102")
+	past := time.Now().AddDate(0, 0, -1).Format("060102")
+	expiredKey := expiryKey{underlying: "BTC-USD", expiry: past}
+
+	// Expiry state left from the time option was live
+	svc.expiryVols[expiredKey] = &expiryVols{strikes: make(map[float64]*strikeVols)}
+	mOptionATMVol.WithLabelValues("BTC-USD", past).Set(0.5)
+	mOptionMarkVol.WithLabelValues("BTC-USD", past, "90000", "C").Set(0.5)
+
+	err := svc.processOptSummary(optSummaryMessage("BTC-USD-"+future+"-90000-C", "BTC-USD-"+past+"-90000-P"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := svc.expiryVols[expiredKey]; ok {
+		t.Error("expired expiry is not pruned")
+	}
+
+	if _, ok := svc.expiryVols[expiryKey{underlying: "BTC-USD", expiry: future}]; !ok {
+		t.Error("live expiry is not tracked")
+	}
+
+	// DeleteLabelValues reports whether series existed
+	if mOptionATMVol.DeleteLabelValues("BTC-USD", past) {
+		t.Error("atm vol of expired expiry is not deleted")
+	}
+
+	if mOptionMarkVol.DeleteLabelValues("BTC-USD", past, "90000", "C") {
+		t.Error("metrics of expired options are not deleted")
+	}
+
+	if mOptionMarkVol.DeleteLabelValues("BTC-USD", past, "90000", "P") {
+		t.Error("summary of expired option is exported")
+	}
+
+	if !mOptionATMVol.DeleteLabelValues("BTC-USD", future) {
+		t.Error("atm vol of live expiry is not exported")
+	}
+}
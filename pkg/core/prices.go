@@ -0,0 +1,104 @@
+package core
+
+import (
+	"github.com/gavt45/okx-exporter/pkg/core/domain/okx"
+	"github.com/gavt45/okx-exporter/pkg/log"
+)
+
+// Values of okx_price type label
+const (
+	PriceTypeLast      = "last"
+	PriceTypeMark      = "mark"
+	PriceTypeIndex     = "index"
+	PriceTypeBuyLimit  = "buy_limit"
+	PriceTypeSellLimit = "sell_limit"
+)
+
+// deviationPercent returns deviation of value from base in percents
+func deviationPercent(value, base float64) float64 {
+	return (value - base) / base * 100
+}
+
+// exportDeviations updates mark-vs-last and last-vs-index deviations of instrument from known prices
+func (s *Service) exportDeviations(instrument okx.Instrument) {
+	last, ok := s.lastPrices[instrument]
+	if !ok || last == 0 {
+		return
+	}
+
+	if mark, ok := s.markPrices[instrument]; ok {
+		mMarkLastDeviation.WithLabelValues(string(instrument)).Set(deviationPercent(mark, last))
+	}
+
+	if index, ok := s.indexPrices[instrument.Underlying()]; ok && index != 0 {
+		mLastIndexDeviation.WithLabelValues(string(instrument)).Set(deviationPercent(last, index))
+	}
+}
+
+func (s *Service) setLastPrice(instrument okx.Instrument, price float64) {
+	s.lastPrices[instrument] = price
+
+	mPrice.WithLabelValues(string(instrument), PriceTypeLast).Set(price)
+	s.exportDeviations(instrument)
+}
+
+func (s *Service) processMarkPrice(data okx.WSData) error {
+	markPrice := okx.WSDataMarkPrice{}
+
+	if err := unmarshalFirst(data, &markPrice, "mark price"); err != nil {
+		return err
+	}
+
+	log.Info("Got mark price data: ", markPrice)
+
+	s.markPrices[markPrice.InstID] = float64(markPrice.MarkPx)
+
+	mPrice.WithLabelValues(string(markPrice.InstID), PriceTypeMark).Set(float64(markPrice.MarkPx))
+	s.exportDeviations(markPrice.InstID)
+
+	return nil
+}
+
+func (s *Service) processIndexTickers(data okx.WSData) error {
+	indexTickers := okx.WSDataIndexTickers{}
+
+	if err := unmarshalFirst(data, &indexTickers, "index tickers"); err != nil {
+		return err
+	}
+
+	log.Info("Got index tickers data: ", indexTickers)
+
+	s.indexPrices[indexTickers.InstID] = float64(indexTickers.IdxPx)
+
+	mPrice.WithLabelValues(string(indexTickers.InstID), PriceTypeIndex).Set(float64(indexTickers.IdxPx))
+
+	for instrument := range s.lastPrices {
+		if instrument.Underlying() == indexTickers.InstID {
+			s.exportDeviations(instrument)
+		}
+	}
+
+	return nil
+}
+
+func (s *Service) processPriceLimit(data okx.WSData) error {
+	priceLimit := okx.WSDataPriceLimit{}
+
+	if err := unmarshalFirst(data, &priceLimit, "price limit"); err != nil {
+		return err
+	}
+
+	log.Info("Got price limit data: ", priceLimit)
+
+	if !priceLimit.Enabled {
+		mPrice.DeleteLabelValues(string(priceLimit.InstID), PriceTypeBuyLimit)
+		mPrice.DeleteLabelValues(string(priceLimit.InstID), PriceTypeSellLimit)
+
+		return nil
+	}
+
+	mPrice.WithLabelValues(string(priceLimit.InstID), PriceTypeBuyLimit).Set(float64(priceLimit.BuyLmt))
+	mPrice.WithLabelValues(string(priceLimit.InstID), PriceTypeSellLimit).Set(float64(priceLimit.SellLmt))
+
+	return nil
+}
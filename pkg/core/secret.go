@@ -0,0 +1,102 @@
+package core
+
+import (
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Redacted is printed instead of secret values
+const Redacted = "[REDACTED]"
+
+// Secret string value which is redacted when printed or marshalled
+type Secret string
+
+func (s Secret) String() string {
+	return Redacted
+}
+
+func (s Secret) GoString() string {
+	return Redacted
+}
+
+func (s Secret) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + Redacted + `"`), nil
+}
+
+func (s Secret) MarshalYAML() (interface{}, error) {
+	return Redacted, nil
+}
+
+// readSecretFile returns file content without surrounding whitespace
+func readSecretFile(path string) (string, error) {
+	data, err := os.ReadFile(path) //nolint:gosec // path is set by operator
+	if err != nil {
+		return "", errors.Wrap(err, "can't read secret file "+path)
+	}
+
+	return strings.TrimSpace(string(data)), nil
+}
+
+// LoadFiles reads values from configured files
+func (c *Credentials) LoadFiles() error {
+	if c.APIKeyFile != "" {
+		apiKey, err := readSecretFile(c.APIKeyFile)
+		if err != nil {
+			return err
+		}
+
+		c.APIKey = apiKey
+	}
+
+	if c.SecretKeyFile != "" {
+		secretKey, err := readSecretFile(c.SecretKeyFile)
+		if err != nil {
+			return err
+		}
+
+		c.SecretKey = Secret(secretKey)
+	}
+
+	if c.PassphraseFile != "" {
+		passphrase, err := readSecretFile(c.PassphraseFile)
+		if err != nil {
+			return err
+		}
+
+		c.Passphrase = Secret(passphrase)
+	}
+
+	return nil
+}
+
+// LoadSecrets reads credentials of all accounts from configured files
+func (c *OKXConfig) LoadSecrets() error {
+	if err := c.Credentials.LoadFiles(); err != nil {
+		return err
+	}
+
+	for i := range c.Accounts {
+		if err := c.Accounts[i].LoadFiles(); err != nil {
+			return errors.Wrap(err, "can't load secrets of account "+c.Accounts[i].Name)
+		}
+	}
+
+	return nil
+}
+
+// Secrets returns all non empty secret values of accounts, they should be redacted from logs
+func (c *OKXConfig) Secrets() []string {
+	var secrets []string
+
+	for _, account := range c.AllAccounts() {
+		for _, secret := range []Secret{account.SecretKey, account.Passphrase} {
+			if secret != "" {
+				secrets = append(secrets, string(secret))
+			}
+		}
+	}
+
+	return secrets
+}
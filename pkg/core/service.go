@@ -2,24 +2,195 @@ package core
 
 import (
 	"encoding/json"
+	"path"
+	"regexp"
 	"time"
 
 	"github.com/gavt45/okx-exporter/pkg/core/domain/okx"
 	"github.com/gavt45/okx-exporter/pkg/log"
 	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
-// List of channels required by core service
-var RequiredChannels = []okx.Channel{
+// List of channels subscribed for instruments not matched in config
+var DefaultChannels = []okx.Channel{
 	okx.ChannelTickers,
 	okx.ChannelCandle1H,
 	okx.ChannelAggregatedTrades,
 }
 
-type Service struct{}
+// Subscriber manages subscriptions on behalf of service
+type Subscriber interface {
+	Subscribe(topics ...okx.WSSubscriptionTopic) error
+	Unsubscribe(topics ...okx.WSSubscriptionTopic) error
+	// Resubscribe unsubscribes from topic and subscribes to it again, i.e. to get fresh order book snapshot
+	Resubscribe(topic okx.WSSubscriptionTopic) error
+}
+
+type Service struct {
+	cfg *OKXConfig
+	sub Subscriber
+
+	books map[bookKey]*orderBook
+
+	lastPrices  map[okx.Instrument]float64
+	markPrices  map[okx.Instrument]float64
+	indexPrices map[okx.Instrument]float64
+
+	expiryVols map[expiryKey]*expiryVols
+
+	// contracts of derivatives got from instruments channel, used to convert contracts to notional
+	contracts map[okx.Instrument]okx.Contract
+
+	// instrumentInfo okx_instrument_info label values exported for instrument
+	instrumentInfo map[okx.Instrument][]string
+
+	// discovered instruments subscribed in discovery mode
+	discovered     map[okx.Instrument]bool
+	discoveryRegex *regexp.Regexp
+}
+
+func NewService(cfg *OKXConfig, sub Subscriber) (*Service, error) {
+	discoveryRegex, err := regexp.Compile(cfg.Discovery.InstIDRegex)
+	if err != nil {
+		return nil, errors.Wrap(err, "can't compile discovery instrument id regex")
+	}
+
+	return &Service{
+		cfg:   cfg,
+		sub:   sub,
+		books: make(map[bookKey]*orderBook),
+
+		lastPrices:  make(map[okx.Instrument]float64),
+		markPrices:  make(map[okx.Instrument]float64),
+		indexPrices: make(map[okx.Instrument]float64),
+
+		expiryVols: make(map[expiryKey]*expiryVols),
+		contracts:  make(map[okx.Instrument]okx.Contract),
+
+		instrumentInfo: make(map[okx.Instrument][]string),
+
+		discovered:     make(map[okx.Instrument]bool),
+		discoveryRegex: discoveryRegex,
+	}, nil
+}
+
+// RequiredChannels returns union of channels configured for all keys matching instrument,
+// or DefaultChannels when there is no such key
+func (s *Service) RequiredChannels(instrument okx.Instrument) []okx.Channel {
+	var channels []okx.Channel
+
+	seen := make(map[okx.Channel]bool)
+
+	for pattern, patternChannels := range s.cfg.Channels {
+		if ok, _ := path.Match(pattern, string(instrument)); !ok {
+			continue
+		}
+
+		for _, channel := range patternChannels {
+			if !seen[channel] {
+				seen[channel] = true
+				channels = append(channels, channel)
+			}
+		}
+	}
+
+	if len(channels) == 0 {
+		return DefaultChannels
+	}
+
+	return channels
+}
+
+// instrumentTopics returns topics of required channels of instrument
+func (s *Service) instrumentTopics(instrument okx.Instrument) []okx.WSSubscriptionTopic {
+	channels := s.RequiredChannels(instrument)
+	topics := make([]okx.WSSubscriptionTopic, 0, len(channels))
+
+	for _, channel := range channels {
+		topics = append(topics, okx.WSSubscriptionTopic{
+			WSArgument: okx.WSArgument{
+				Channel: channel,
+			},
+			InstID: instrument,
+		})
+	}
+
+	return topics
+}
+
+// Topics returns all topics service needs: required channels of every configured or discovered instrument,
+// instruments channel for discovery and configured topics
+func (s *Service) Topics() []okx.WSSubscriptionTopic {
+	var topics []okx.WSSubscriptionTopic
+
+	if s.cfg.Discovery.Enabled {
+		for _, instType := range s.cfg.Discovery.InstTypes {
+			topics = append(topics, okx.WSSubscriptionTopic{
+				WSArgument: okx.WSArgument{
+					Channel:  okx.ChannelInstruments,
+					InstType: instType,
+				},
+			})
+		}
+
+		for instrument := range s.discovered {
+			topics = append(topics, s.instrumentTopics(instrument)...)
+		}
+	} else {
+		for _, instrument := range s.cfg.Instruments {
+			topics = append(topics, s.instrumentTopics(instrument)...)
+		}
+	}
+
+	for _, topic := range s.cfg.Topics {
+		topics = append(topics, okx.WSSubscriptionTopic{
+			WSArgument: okx.WSArgument{
+				Channel:    topic.Channel,
+				InstType:   topic.InstType,
+				InstFamily: topic.InstFamily,
+			},
+		})
+	}
+
+	return topics
+}
+
+// setTimestamp exports unix time, empty timestamp deletes series instead of exporting zero time
+func setTimestamp(gauge *prometheus.GaugeVec, ts time.Time, labels ...string) {
+	if ts.IsZero() {
+		gauge.DeleteLabelValues(labels...)
+		return
+	}
 
-func (s Service) RequiredChannels() []okx.Channel {
-	return RequiredChannels
+	gauge.WithLabelValues(labels...).Set(float64(ts.Unix()))
+}
+
+// setTopOfBook exports best bid and ask from tickers, series of empty side and spread are deleted
+func (s *Service) setTopOfBook(tickers okx.WSDataTickers) {
+	instID := string(tickers.InstID)
+
+	if tickers.HasBid() {
+		mBidPrice.WithLabelValues(instID).Set(float64(tickers.BidPx))
+		mBidSize.WithLabelValues(instID).Set(float64(tickers.BidSz))
+	} else {
+		mBidPrice.DeleteLabelValues(instID)
+		mBidSize.DeleteLabelValues(instID)
+	}
+
+	if tickers.HasAsk() {
+		mAskPrice.WithLabelValues(instID).Set(float64(tickers.AskPx))
+		mAskSize.WithLabelValues(instID).Set(float64(tickers.AskSz))
+	} else {
+		mAskPrice.DeleteLabelValues(instID)
+		mAskSize.DeleteLabelValues(instID)
+	}
+
+	if tickers.HasBid() && tickers.HasAsk() {
+		mSpread.WithLabelValues(instID).Set(tickers.Spread())
+	} else {
+		mSpread.DeleteLabelValues(instID)
+	}
 }
 
 func (s *Service) ProcessMessage(data okx.WSData) error {
@@ -29,51 +200,172 @@ func (s *Service) ProcessMessage(data okx.WSData) error {
 
 	now := time.Now()
 
-	switch data.Arg.Channel { //nolint:exhaustive // instruments are not implemented yet, so we don't subscribe to them
-	case okx.ChannelTickers:
+	channel := data.Arg.Channel
+
+	switch {
+	case channel == okx.ChannelTickers:
 		tickers := okx.WSDataTickers{}
 
-		err := json.Unmarshal(data.Data[0], &tickers)
-		if err != nil {
-			return errors.Wrap(err, "can't parse data as data for tickers")
+		if err := unmarshalFirst(data, &tickers, "tickers"); err != nil {
+			return err
 		}
 
 		latency := now.Sub(tickers.TS.Time).Seconds()
 
 		log.Info("Got tickers data: ", tickers)
 
-		mLastPrice.WithLabelValues(string(tickers.InstID)).Set(tickers.LastFloat())
-		mLatency.WithLabelValues(string(tickers.InstID)).Observe(float64(latency))
-	case okx.ChannelCandle1H:
-		candle1H := okx.WSDataCandle{}
+		instID := string(tickers.InstID)
 
-		err := json.Unmarshal(data.Data[0], &candle1H)
-		if err != nil {
-			return errors.Wrap(err, "can't parse data as data for candle")
+		s.setLastPrice(tickers.InstID, tickers.LastFloat())
+		mLastSize.WithLabelValues(instID).Set(float64(tickers.LastSz))
+		s.setTopOfBook(tickers)
+		mOpen24h.WithLabelValues(instID).Set(float64(tickers.Open24h))
+		mHigh24h.WithLabelValues(instID).Set(float64(tickers.High24h))
+		mLow24h.WithLabelValues(instID).Set(float64(tickers.Low24h))
+		mChange24h.WithLabelValues(instID).Set(tickers.Change24hPercent())
+		mVolume24h.WithLabelValues(instID).Set(float64(tickers.Vol24h))
+		mVolumeCcy24h.WithLabelValues(instID).Set(float64(tickers.VolCcy24h))
+		mSodUtc0.WithLabelValues(instID).Set(float64(tickers.SodUtc0))
+		mSodUtc8.WithLabelValues(instID).Set(float64(tickers.SodUtc8))
+		mLatency.WithLabelValues(instID).Observe(float64(latency))
+	case channel.IsCandle():
+		candle := okx.WSDataCandle{}
+
+		if err := unmarshalFirst(data, &candle, "candle"); err != nil {
+			return err
 		}
 
-		log.Info("Got 1H candle data: ", candle1H)
+		interval := channel.CandleInterval()
+
+		log.Infof("Got %s candle data: %v", interval, candle)
 
-		mLastTS.WithLabelValues(string(data.Arg.InstID), okx.Candle1H).Set(float64(candle1H.TS.UnixMilli()))
-		mLastOpen.WithLabelValues(string(data.Arg.InstID), okx.Candle1H).Set(candle1H.Open)
-		mLastHigh.WithLabelValues(string(data.Arg.InstID), okx.Candle1H).Set(candle1H.High)
-		mLastLow.WithLabelValues(string(data.Arg.InstID), okx.Candle1H).Set(candle1H.Low)
-		mLastClose.WithLabelValues(string(data.Arg.InstID), okx.Candle1H).Set(candle1H.Close)
-		mLastVolume.WithLabelValues(string(data.Arg.InstID), okx.Candle1H).Set(candle1H.Volume)
-	case okx.ChannelAggregatedTrades:
+		mLastTS.WithLabelValues(string(data.Arg.InstID), interval).Set(float64(candle.TS.UnixMilli()))
+		mLastOpen.WithLabelValues(string(data.Arg.InstID), interval).Set(candle.Open)
+		mLastHigh.WithLabelValues(string(data.Arg.InstID), interval).Set(candle.High)
+		mLastLow.WithLabelValues(string(data.Arg.InstID), interval).Set(candle.Low)
+		mLastClose.WithLabelValues(string(data.Arg.InstID), interval).Set(candle.Close)
+		mLastVolume.WithLabelValues(string(data.Arg.InstID), interval).Set(candle.Volume)
+	case channel.IsBook():
+		return s.processBook(data)
+	case channel == okx.ChannelAggregatedTrades || channel == okx.ChannelTrades || channel == okx.ChannelTradesAll:
 		for _, tradeData := range data.Data {
 			trade := okx.WSDataTrade{}
 
 			if err := json.Unmarshal(tradeData, &trade); err != nil {
-				return errors.Wrap(err, "can't parse data as data for trade")
+				return parseError(data, err, "trade")
 			}
 
 			log.Info("Got trade data: ", trade)
 
-			mTradeSizeHist.WithLabelValues(string(data.Arg.InstID)).Observe(trade.SZFloat())
+			instID, side := string(data.Arg.InstID), string(trade.Side)
+
+			if channel == okx.ChannelAggregatedTrades {
+				mTradeSizeHist.WithLabelValues(instID).Observe(trade.SZFloat())
+			}
+
+			mTradesCount.WithLabelValues(instID, string(channel), side).Add(trade.TradesCount())
+			mTradesVolume.WithLabelValues(instID, string(channel), side).Add(trade.SZFloat())
+
+			if notional, ok := s.notional(data.Arg.InstID, float64(trade.PX), trade.SZFloat()); ok {
+				mTradesNotional.WithLabelValues(instID, string(channel), side).Add(notional)
+			}
+		}
+	case channel == okx.ChannelFundingRate:
+		fundingRate := okx.WSDataFundingRate{}
+
+		if err := unmarshalFirst(data, &fundingRate, "funding rate"); err != nil {
+			return err
+		}
+
+		log.Info("Got funding rate data: ", fundingRate)
+
+		instID := string(fundingRate.InstID)
+
+		mFundingRate.WithLabelValues(instID).Set(float64(fundingRate.FundingRate))
+		mFundingTimeLeft.WithLabelValues(instID).Set(fundingRate.FundingTime.Sub(now).Seconds())
+
+		// okx deprecated predicted rate and sends it empty, it is not exported then
+		if fundingRate.NextFundingRate.Set {
+			mNextFundingRate.WithLabelValues(instID).Set(float64(fundingRate.NextFundingRate.Value))
+		} else {
+			mNextFundingRate.DeleteLabelValues(instID)
+		}
+	case channel == okx.ChannelOpenInterest:
+		openInterest := okx.WSDataOpenInterest{}
+
+		if err := unmarshalFirst(data, &openInterest, "open interest"); err != nil {
+			return err
+		}
+
+		log.Info("Got open interest data: ", openInterest)
+
+		mOpenInterest.WithLabelValues(string(openInterest.InstID)).Set(float64(openInterest.OI))
+		mOpenInterestCcy.WithLabelValues(string(openInterest.InstID)).Set(float64(openInterest.OICcy))
+	case channel == okx.ChannelMarkPrice:
+		return s.processMarkPrice(data)
+	case channel == okx.ChannelIndexTickers:
+		return s.processIndexTickers(data)
+	case channel == okx.ChannelPriceLimit:
+		return s.processPriceLimit(data)
+	case channel == okx.ChannelLiquidations:
+		for _, liquidationData := range data.Data {
+			liquidation := okx.WSDataLiquidation{}
+
+			if err := json.Unmarshal(liquidationData, &liquidation); err != nil {
+				return parseError(data, err, "liquidation orders")
+			}
+
+			log.Info("Got liquidation orders data: ", liquidation)
+
+			for _, detail := range liquidation.Details {
+				instID, side := string(liquidation.InstID), string(detail.Side)
+
+				mLiquidationsSize.WithLabelValues(instID, side).Add(float64(detail.Sz))
+				mLiquidationSizeHist.WithLabelValues(instID).Observe(float64(detail.Sz))
+
+				if notional, ok := s.notional(liquidation.InstID, float64(detail.BkPx), float64(detail.Sz)); ok {
+					mLiquidationsNotional.WithLabelValues(instID, side).Add(notional)
+				}
+			}
+		}
+	case channel == okx.ChannelOptSummary:
+		return s.processOptSummary(data)
+	case channel == okx.ChannelEstimatedPrice:
+		for _, priceData := range data.Data {
+			estimatedPrice := okx.WSDataEstimatedPrice{}
+
+			if err := json.Unmarshal(priceData, &estimatedPrice); err != nil {
+				return parseError(data, err, "estimated price")
+			}
+
+			log.Info("Got estimated price data: ", estimatedPrice)
+
+			mEstimatedPrice.WithLabelValues(string(estimatedPrice.InstID), estimatedPrice.SettleType).
+				Set(float64(estimatedPrice.SettlePx))
+		}
+	case channel == okx.ChannelStatus:
+		for _, statusData := range data.Data {
+			status := okx.WSDataStatus{}
+
+			if err := json.Unmarshal(statusData, &status); err != nil {
+				return parseError(data, err, "status")
+			}
+
+			log.Info("Got status data: ", status)
+
+			underMaintenance := 0.0
+			if status.State == okx.MaintenanceStateOngoing {
+				underMaintenance = 1
+			}
+
+			setTimestamp(mMaintenanceStart, status.Begin.Time, status.ServiceType)
+			setTimestamp(mMaintenanceEnd, status.End.Time, status.ServiceType)
+			mUnderMaintenance.WithLabelValues(status.ServiceType).Set(underMaintenance)
 		}
+	case channel == okx.ChannelInstruments:
+		return s.processInstruments(data)
 	default:
-		log.Warn("Unknown channel: " + data.Arg.Channel)
+		log.Warn("Unknown channel: " + channel)
 	}
 
 	return nil
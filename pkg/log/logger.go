@@ -2,7 +2,18 @@
 package log
 
 import (
+	"fmt"
 	"log"
+	"strings"
+	"sync"
+)
+
+// Redacted is logged instead of registered secrets
+const Redacted = "[REDACTED]"
+
+var (
+	secretsMu sync.RWMutex
+	secrets   []string
 )
 
 // Logger instance.
@@ -27,6 +38,30 @@ func SetLogger(logger ILogger) {
 	Logger = logger
 }
 
+// AddSecret registers value which is replaced with Redacted in every log line
+func AddSecret(secret string) {
+	if secret == "" {
+		return
+	}
+
+	secretsMu.Lock()
+	defer secretsMu.Unlock()
+
+	secrets = append(secrets, secret)
+}
+
+// redact replaces registered secrets in message
+func redact(msg string) string {
+	secretsMu.RLock()
+	defer secretsMu.RUnlock()
+
+	for _, secret := range secrets {
+		msg = strings.ReplaceAll(msg, secret, Redacted)
+	}
+
+	return msg
+}
+
 func initIfNull() {
 	if Logger == nil {
 		zlog, err := NewZapLogger(true)
@@ -42,59 +77,59 @@ func initIfNull() {
 // Errorf - log error with formatting.
 func Errorf(format string, args ...interface{}) {
 	initIfNull()
-	Logger.Errorf(format, args...)
+	Logger.Errorf("%s", redact(fmt.Sprintf(format, args...)))
 }
 
 // Error - log error without formatting.
 func Error(args ...interface{}) {
 	initIfNull()
-	Logger.Error(args...)
+	Logger.Error(redact(fmt.Sprint(args...)))
 }
 
 // Fatalf - log and fall with error with formatting.
 func Fatalf(format string, args ...interface{}) {
 	initIfNull()
-	Logger.Fatalf(format, args...)
+	Logger.Fatalf("%s", redact(fmt.Sprintf(format, args...)))
 }
 
 // Fatal - log and fall with error.
 func Fatal(args ...interface{}) {
 	initIfNull()
-	Logger.Fatal(args...)
+	Logger.Fatal(redact(fmt.Sprint(args...)))
 }
 
 // Infof - log info messages with formatting.
 func Infof(format string, args ...interface{}) {
 	initIfNull()
-	Logger.Infof(format, args...)
+	Logger.Infof("%s", redact(fmt.Sprintf(format, args...)))
 }
 
 // Info - log info messages without formatting.
 func Info(args ...interface{}) {
 	initIfNull()
-	Logger.Info(args...)
+	Logger.Info(redact(fmt.Sprint(args...)))
 }
 
 // Warnf - log warnings with formatting.
 func Warnf(format string, args ...interface{}) {
 	initIfNull()
-	Logger.Warnf(format, args...)
+	Logger.Warnf("%s", redact(fmt.Sprintf(format, args...)))
 }
 
 // Warn - log warnings without formatting.
 func Warn(args ...interface{}) {
 	initIfNull()
-	Logger.Warn(args...)
+	Logger.Warn(redact(fmt.Sprint(args...)))
 }
 
 // Debugf - log debug messages with formatting.
 func Debugf(format string, args ...interface{}) {
 	initIfNull()
-	Logger.Debugf(format, args...)
+	Logger.Debugf("%s", redact(fmt.Sprintf(format, args...)))
 }
 
 // Debug - log debug messages without formatting.
 func Debug(args ...interface{}) {
 	initIfNull()
-	Logger.Debug(args...)
+	Logger.Debug(redact(fmt.Sprint(args...)))
 }